@@ -0,0 +1,242 @@
+package facetengine
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// chunkBits is the number of values in each chunk of a Bitmap.
+const chunkBits = 1 << 16
+
+// Bitmap is a compressed set of uint32.  Values are split in to chunks of 65536 by their high 16 bits, chunks with no
+// values are not stored and each chunk only keeps the words up to its highest value.  And, Or and AndNot work a 64 bit
+// word at a time.
+type Bitmap struct {
+	chunks []*chunk
+}
+
+type chunk struct {
+	key   uint16
+	words []uint64
+}
+
+// NewBitmap create an empty one.
+func NewBitmap() *Bitmap {
+	return &Bitmap{}
+}
+
+// BitmapOf create a bitmap holding the values.
+func BitmapOf(values ...uint32) *Bitmap {
+	b := NewBitmap()
+	for _, value := range values {
+		b.Add(value)
+	}
+	return b
+}
+
+// find return the index of the chunk with the key, or where it would be inserted.
+func (b *Bitmap) find(key uint16) (int, bool) {
+	i := sort.Search(len(b.chunks), func(i int) bool { return b.chunks[i].key >= key })
+	return i, i < len(b.chunks) && b.chunks[i].key == key
+}
+
+// Add a value
+func (b *Bitmap) Add(value uint32) {
+	key, low := uint16(value>>16), value&(chunkBits-1)
+	i, ok := b.find(key)
+	if !ok {
+		b.chunks = append(b.chunks, nil)
+		copy(b.chunks[i+1:], b.chunks[i:])
+		b.chunks[i] = &chunk{key: key}
+	}
+	c := b.chunks[i]
+	word := int(low / 64)
+	if word >= cap(c.words) {
+		capacity := 2 * cap(c.words)
+		if capacity <= word {
+			capacity = word + 1
+		}
+		words := make([]uint64, len(c.words), capacity)
+		copy(words, c.words)
+		c.words = words
+	}
+	if word >= len(c.words) {
+		// words past the old length may hold values from before a Remove trimmed them.
+		old := len(c.words)
+		c.words = c.words[:word+1]
+		for k := old; k <= word; k++ {
+			c.words[k] = 0
+		}
+	}
+	c.words[word] |= 1 << (low % 64)
+}
+
+// Remove a value
+func (b *Bitmap) Remove(value uint32) {
+	key, low := uint16(value>>16), value&(chunkBits-1)
+	i, ok := b.find(key)
+	if !ok {
+		return
+	}
+	c := b.chunks[i]
+	word := int(low / 64)
+	if word >= len(c.words) {
+		return
+	}
+	c.words[word] &^= 1 << (low % 64)
+	c.trim()
+	if len(c.words) == 0 {
+		b.chunks = append(b.chunks[:i], b.chunks[i+1:]...)
+	}
+}
+
+// Contains does this value exist in the bitmap.
+func (b *Bitmap) Contains(value uint32) bool {
+	key, low := uint16(value>>16), value&(chunkBits-1)
+	i, ok := b.find(key)
+	if !ok {
+		return false
+	}
+	c := b.chunks[i]
+	word := int(low / 64)
+	return word < len(c.words) && c.words[word]&(1<<(low%64)) != 0
+}
+
+// Len return the number of values
+func (b *Bitmap) Len() int {
+	count := 0
+	for _, c := range b.chunks {
+		for _, word := range c.words {
+			count += bits.OnesCount64(word)
+		}
+	}
+	return count
+}
+
+// Clone return a copy that can be changed without changing this one.
+func (b *Bitmap) Clone() *Bitmap {
+	result := &Bitmap{chunks: make([]*chunk, len(b.chunks))}
+	for i, c := range b.chunks {
+		result.chunks[i] = &chunk{key: c.key, words: append([]uint64(nil), c.words...)}
+	}
+	return result
+}
+
+// And return the values in both bitmaps.
+func (b *Bitmap) And(other *Bitmap) *Bitmap {
+	result := NewBitmap()
+	i, j := 0, 0
+	for i < len(b.chunks) && j < len(other.chunks) {
+		x, y := b.chunks[i], other.chunks[j]
+		switch {
+		case x.key < y.key:
+			i++
+		case x.key > y.key:
+			j++
+		default:
+			if len(x.words) > len(y.words) {
+				x, y = y, x
+			}
+			words := make([]uint64, len(x.words))
+			for k := range words {
+				words[k] = x.words[k] & y.words[k]
+			}
+			result.appendChunk(x.key, words)
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// Or return the values in either bitmap.
+func (b *Bitmap) Or(other *Bitmap) *Bitmap {
+	result := &Bitmap{chunks: make([]*chunk, 0, len(b.chunks)+len(other.chunks))}
+	i, j := 0, 0
+	for i < len(b.chunks) || j < len(other.chunks) {
+		switch {
+		case j == len(other.chunks) || (i < len(b.chunks) && b.chunks[i].key < other.chunks[j].key):
+			result.appendChunk(b.chunks[i].key, append([]uint64(nil), b.chunks[i].words...))
+			i++
+		case i == len(b.chunks) || other.chunks[j].key < b.chunks[i].key:
+			result.appendChunk(other.chunks[j].key, append([]uint64(nil), other.chunks[j].words...))
+			j++
+		default:
+			x, y := b.chunks[i], other.chunks[j]
+			if len(x.words) < len(y.words) {
+				x, y = y, x
+			}
+			words := append([]uint64(nil), x.words...)
+			for k, word := range y.words {
+				words[k] |= word
+			}
+			result.appendChunk(x.key, words)
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// AndNot return the values in this bitmap that are not in the other.
+func (b *Bitmap) AndNot(other *Bitmap) *Bitmap {
+	result := NewBitmap()
+	j := 0
+	for _, x := range b.chunks {
+		for j < len(other.chunks) && other.chunks[j].key < x.key {
+			j++
+		}
+		words := append([]uint64(nil), x.words...)
+		if j < len(other.chunks) && other.chunks[j].key == x.key {
+			for k, word := range other.chunks[j].words {
+				if k == len(words) {
+					break
+				}
+				words[k] &^= word
+			}
+		}
+		result.appendChunk(x.key, words)
+	}
+	return result
+}
+
+// appendChunk add a chunk with a key greater than any already in the bitmap, dropping it if it is empty.
+func (b *Bitmap) appendChunk(key uint16, words []uint64) {
+	c := &chunk{key: key, words: words}
+	c.trim()
+	if len(c.words) > 0 {
+		b.chunks = append(b.chunks, c)
+	}
+}
+
+// trim drop the empty words after the highest value.
+func (c *chunk) trim() {
+	n := len(c.words)
+	for n > 0 && c.words[n-1] == 0 {
+		n--
+	}
+	c.words = c.words[:n]
+}
+
+// ForEach call fn with each value in increasing order.
+func (b *Bitmap) ForEach(fn func(value uint32)) {
+	for _, c := range b.chunks {
+		base := uint32(c.key) << 16
+		for k, word := range c.words {
+			for word != 0 {
+				value := base + uint32(k*64+bits.TrailingZeros64(word))
+				word &= word - 1
+				fn(value)
+			}
+		}
+	}
+}
+
+// ToArray return the values in increasing order.
+func (b *Bitmap) ToArray() []uint32 {
+	results := make([]uint32, 0, b.Len())
+	b.ForEach(func(value uint32) {
+		results = append(results, value)
+	})
+	return results
+}
@@ -0,0 +1,127 @@
+package facetengine
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBitmap(t *testing.T) {
+	b := BitmapOf(3, 70000, 64, 0)
+	require.Equal(t, 4, b.Len())
+	require.Equal(t, []uint32{0, 3, 64, 70000}, b.ToArray())
+	require.True(t, b.Contains(70000))
+	require.False(t, b.Contains(65))
+	require.False(t, b.Contains(200000))
+
+	b.Remove(70000)
+	b.Remove(64)
+	b.Remove(12345678)
+	require.Equal(t, []uint32{0, 3}, b.ToArray())
+	require.Equal(t, 1, len(b.chunks))
+	require.Equal(t, 1, len(b.chunks[0].words))
+
+	b.Add(127)
+	require.Equal(t, []uint32{0, 3, 127}, b.ToArray())
+}
+
+func TestBitmapClone(t *testing.T) {
+	b := BitmapOf(1, 2)
+	clone := b.Clone()
+	clone.Add(3)
+	require.Equal(t, []uint32{1, 2}, b.ToArray())
+	require.Equal(t, []uint32{1, 2, 3}, clone.ToArray())
+}
+
+func TestBitmapOperations(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		x, xValues := randomBitmap(random)
+		y, yValues := randomBitmap(random)
+		and, or, andNot := map[uint32]bool{}, map[uint32]bool{}, map[uint32]bool{}
+		for value := range xValues {
+			or[value] = true
+			if yValues[value] {
+				and[value] = true
+			} else {
+				andNot[value] = true
+			}
+		}
+		for value := range yValues {
+			or[value] = true
+		}
+		require.Equal(t, sortedKeys(and), x.And(y).ToArray())
+		require.Equal(t, sortedKeys(or), x.Or(y).ToArray())
+		require.Equal(t, sortedKeys(andNot), x.AndNot(y).ToArray())
+		require.Equal(t, len(and), x.And(y).Len())
+	}
+}
+
+func TestBitmapOperationsEmpty(t *testing.T) {
+	b := BitmapOf(1, 100000)
+	require.Equal(t, []uint32{}, b.And(NewBitmap()).ToArray())
+	require.Equal(t, []uint32{1, 100000}, b.Or(NewBitmap()).ToArray())
+	require.Equal(t, []uint32{1, 100000}, NewBitmap().Or(b).ToArray())
+	require.Equal(t, []uint32{}, b.AndNot(b).ToArray())
+	require.Empty(t, b.AndNot(b).chunks)
+}
+
+func randomBitmap(random *rand.Rand) (*Bitmap, map[uint32]bool) {
+	b := NewBitmap()
+	values := map[uint32]bool{}
+	for i := 0; i < random.Intn(1000); i++ {
+		value := uint32(random.Intn(300000))
+		b.Add(value)
+		values[value] = true
+	}
+	return b, values
+}
+
+func sortedKeys(values map[uint32]bool) []uint32 {
+	results := []uint32{}
+	for value := range values {
+		results = append(results, value)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i] < results[j] })
+	return results
+}
+
+func BenchmarkBitmapAnd100k(b *testing.B) {
+	x, y := NewBitmap(), NewBitmap()
+	for i := uint32(0); i < 100000; i++ {
+		if i%2 == 0 {
+			x.Add(i)
+		}
+		if i%3 == 0 {
+			y.Add(i)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x.And(y)
+	}
+}
+
+func BenchmarkMapAnd100k(b *testing.B) {
+	x, y := map[string]bool{}, map[string]bool{}
+	for i := 0; i < 100000; i++ {
+		if i%2 == 0 {
+			x[fmt.Sprintf("record %d", i)] = true
+		}
+		if i%3 == 0 {
+			y[fmt.Sprintf("record %d", i)] = true
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		results := map[string]bool{}
+		for k := range x {
+			if y[k] {
+				results[k] = true
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	facetengine "github.com/codeallthethingz/wasm-facet-engine"
+)
+
+// parseFilter read a filter written as group/facet:range or group/facet:values.  A range is two numbers in square
+// brackets for inclusive or round brackets for exclusive, e.g. "area (cube)/side:[8,12)".  Anything else is a comma
+// separated list of values for a categorical facet, e.g. "shaft (screwthread)/material:steel,brass".  The group ends
+// at the first / and the facet at the next :, so values can hold either.  A backslash escapes the character after it,
+// e.g. "a\/b/c:x" for the group "a/b" or "g/f:a\,b" for the single value "a,b".
+func parseFilter(spec string) (*facetengine.Expression, error) {
+	facetGroupName, rest, ok := cut(spec, '/')
+	if !ok || facetGroupName == "" {
+		return nil, facetengine.NewError(facetengine.ErrInvalidFilter, "filter %q must name a group and facet as group/facet", spec)
+	}
+	facetName, criteria, ok := cut(rest, ':')
+	if !ok {
+		return nil, facetengine.NewError(facetengine.ErrInvalidFilter, "filter %q has no : between the facet and the range", spec)
+	}
+	if facetName == "" {
+		return nil, facetengine.NewError(facetengine.ErrInvalidFilter, "filter %q must name a group and facet as group/facet", spec)
+	}
+	criteria = strings.TrimSpace(criteria)
+	if criteria == "" {
+		return nil, facetengine.NewError(facetengine.ErrInvalidFilter, "filter %q has nothing after the :", spec)
+	}
+	if strings.HasPrefix(criteria, "[") || strings.HasPrefix(criteria, "(") {
+		min, max, err := parseRange(criteria)
+		if err != nil {
+			return nil, facetengine.NewError(facetengine.ErrInvalidFilter, "filter %q: %v", spec, err)
+		}
+		return facetengine.NewFilter(facetGroupName, facetName, min, max), nil
+	}
+	values := []string{}
+	for more := true; more; {
+		var value string
+		value, criteria, more = cut(criteria, ',')
+		values = append(values, value)
+	}
+	if len(values) == 1 {
+		return facetengine.Equals(facetGroupName, facetName, values[0]), nil
+	}
+	return facetengine.In(facetGroupName, facetName, values...), nil
+}
+
+// cut split s at the first separator that isn't escaped with a backslash.  The part before it is returned with its
+// escapes removed, the part after it as it was written.
+func cut(s string, separator byte) (string, string, bool) {
+	var before strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			before.WriteByte(s[i])
+		case s[i] == separator:
+			return before.String(), s[i+1:], true
+		default:
+			before.WriteByte(s[i])
+		}
+	}
+	return before.String(), "", false
+}
+
+// parseRange read [min,max], (min,max), [min,max) or (min,max].
+func parseRange(criteria string) (facetengine.Range, facetengine.Range, error) {
+	last := criteria[len(criteria)-1]
+	if last != ']' && last != ')' {
+		return nil, nil, facetengine.NewError(facetengine.ErrInvalidFilter, "range must end with ] or )")
+	}
+	bounds := strings.Split(criteria[1:len(criteria)-1], ",")
+	if len(bounds) != 2 {
+		return nil, nil, facetengine.NewError(facetengine.ErrInvalidFilter, "range must be two numbers separated by a comma")
+	}
+	min, err := strconv.ParseFloat(strings.TrimSpace(bounds[0]), 64)
+	if err != nil {
+		return nil, nil, facetengine.NewError(facetengine.ErrInvalidNumber, "%s is not a number", bounds[0])
+	}
+	max, err := strconv.ParseFloat(strings.TrimSpace(bounds[1]), 64)
+	if err != nil {
+		return nil, nil, facetengine.NewError(facetengine.ErrInvalidNumber, "%s is not a number", bounds[1])
+	}
+	return toRange(criteria[0] == '[', min), toRange(last == ']', max), nil
+}
+
+func toRange(inclusive bool, value float64) facetengine.Range {
+	if inclusive {
+		return facetengine.Inclusive(value)
+	}
+	return facetengine.Exclusive(value)
+}
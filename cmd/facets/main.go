@@ -0,0 +1,155 @@
+// Command facets loads a records file with a facet path and prints the facets and ids matching the filters, so a
+// configuration can be tried without a browser.
+//
+//	facets -records records.json -config facet-path.json -filter "area (cube)/side:[8,12)" -format table
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	facetengine "github.com/codeallthethingz/wasm-facet-engine"
+)
+
+// filters collects every -filter flag.
+type filters []string
+
+func (f *filters) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *filters) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run parse the arguments, query the records and write the results to out.
+func run(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("facets", flag.ContinueOnError)
+	recordsFile := flags.String("records", "", "json file holding an array of records")
+	configFile := flags.String("config", "", "json file holding the facet path")
+	format := flags.String("format", "json", "output format, json or table")
+	var filterSpecs filters
+	flags.Var(&filterSpecs, "filter", `filter as group/facet:[min,max) or group/facet:value,value, may be repeated`)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *recordsFile == "" || *configFile == "" {
+		return facetengine.NewError(facetengine.ErrInvalidArgument, "-records and -config are required")
+	}
+	if *format != "json" && *format != "table" {
+		return facetengine.NewError(facetengine.ErrInvalidArgument, "unknown format %s, expected json or table", *format)
+	}
+
+	engine, err := load(*recordsFile, *configFile)
+	if err != nil {
+		return err
+	}
+	for _, spec := range filterSpecs {
+		expression, err := parseFilter(spec)
+		if err != nil {
+			return err
+		}
+		if _, err := engine.AddExpression(expression); err != nil {
+			return err
+		}
+	}
+	ids, facetGroups, err := engine.Query()
+	if err != nil {
+		return err
+	}
+	if *format == "table" {
+		return writeTable(out, ids, facetGroups)
+	}
+	return writeJSON(out, ids, facetGroups)
+}
+
+// load create an engine from the files.
+func load(recordsFile string, configFile string) (*facetengine.FacetEngine, error) {
+	configBytes, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	facetPath := &facetengine.FacetPath{}
+	if err := json.Unmarshal(configBytes, facetPath); err != nil {
+		return nil, facetengine.ToError(err)
+	}
+	recordsBytes, err := ioutil.ReadFile(recordsFile)
+	if err != nil {
+		return nil, err
+	}
+	engine, _, err := facetengine.NewFacetEngine(string(recordsBytes), facetPath)
+	return engine, err
+}
+
+// writeJSON write {ids, facets}, the same as query() resolves to.
+func writeJSON(out io.Writer, ids []string, facetGroups map[string]*facetengine.FacetGroup) error {
+	sort.Strings(ids)
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]interface{}{"ids": ids, "facets": facetGroups})
+}
+
+// writeTable write a row per facet followed by the matching ids.
+func writeTable(out io.Writer, ids []string, facetGroups map[string]*facetengine.FacetGroup) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tFACET\tCOUNT\tVALUES")
+	groupNames := make([]string, 0, len(facetGroups))
+	for name := range facetGroups {
+		groupNames = append(groupNames, name)
+	}
+	sort.Strings(groupNames)
+	for _, groupName := range groupNames {
+		facetGroup := facetGroups[groupName]
+		facetNames := make([]string, 0, len(facetGroup.Facets))
+		for name := range facetGroup.Facets {
+			facetNames = append(facetNames, name)
+		}
+		sort.Strings(facetNames)
+		for _, facetName := range facetNames {
+			facet := facetGroup.Facets[facetName]
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", groupName, facetName, facet.Count, describeValues(facet))
+		}
+	}
+	fmt.Fprintf(w, "\n%d IDS\n", len(ids))
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintln(w, id)
+	}
+	return w.Flush()
+}
+
+// describeValues summarise a facet in one cell, the range of a numeric facet or the counts of a categorical one.
+func describeValues(facet *facetengine.Facet) string {
+	if !facet.Categorical && facet.Statistics != nil && facet.Statistics.Count > 0 {
+		return fmt.Sprintf("%s to %s", formatNumber(facet.Statistics.Min), formatNumber(facet.Statistics.Max))
+	}
+	values := make([]string, 0, len(facet.Counts))
+	for value := range facet.Counts {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	for i, value := range values {
+		values[i] = fmt.Sprintf("%s (%d)", value, facet.Counts[value])
+	}
+	return strings.Join(values, ", ")
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
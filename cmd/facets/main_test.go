@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	facetengine "github.com/codeallthethingz/wasm-facet-engine"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = `{"arrayDotNotation":"bounds","nameFieldDotNotation":"name","nameMetaDotNotation":"boundingType.name","valueMapDotNotation":"boundingType.measurements","categoricalFacets":["material"]}`
+
+var testRecords = `[
+	{"id": "1", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "1.5", "material": "steel"}}}]},
+	{"id": "2", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "2", "material": "stainless steel"}}}]},
+	{"id": "3", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "2", "material": "brass"}}}]}
+]`
+
+func testFiles(t *testing.T) (string, string, func()) {
+	dir, err := ioutil.TempDir("", "facets")
+	require.Nil(t, err)
+	recordsFile, configFile := filepath.Join(dir, "records.json"), filepath.Join(dir, "config.json")
+	require.Nil(t, ioutil.WriteFile(recordsFile, []byte(testRecords), 0600))
+	require.Nil(t, ioutil.WriteFile(configFile, []byte(testConfig), 0600))
+	return recordsFile, configFile, func() { os.RemoveAll(dir) }
+}
+
+func TestParseFilter(t *testing.T) {
+	expression, err := parseFilter("area (cube)/side:[8,12)")
+	require.Nil(t, err)
+	require.Equal(t, facetengine.NewFilter("area (cube)", "side", facetengine.Inclusive(8), facetengine.Exclusive(12)), expression)
+
+	expression, err = parseFilter(`a\/b/c:(-1.5, 2]`)
+	require.Nil(t, err)
+	require.Equal(t, facetengine.NewFilter("a/b", "c", facetengine.Exclusive(-1.5), facetengine.Inclusive(2)), expression)
+
+	expression, err = parseFilter("event/time:12:30,https://example.com/a")
+	require.Nil(t, err)
+	require.Equal(t, facetengine.In("event", "time", "12:30", "https://example.com/a"), expression)
+
+	expression, err = parseFilter(`a\:b/c\:d\/e:x\,y\\z`)
+	require.Nil(t, err)
+	require.Equal(t, facetengine.Equals("a:b", "c:d/e", `x,y\z`), expression)
+
+	expression, err = parseFilter("shaft (screwthread)/material:steel")
+	require.Nil(t, err)
+	require.Equal(t, facetengine.Equals("shaft (screwthread)", "material", "steel"), expression)
+
+	expression, err = parseFilter("shaft (screwthread)/material:steel,brass")
+	require.Nil(t, err)
+	require.Equal(t, facetengine.In("shaft (screwthread)", "material", "steel", "brass"), expression)
+
+	for _, spec := range []string{"side", "side:[1,2]", "/side:[1,2]", "cube/:[1,2]", "cube/side:", "cube/side:[1,2", "cube/side:[1]", "cube/side:[a,2]", "cube/side:[1,b)", `cube\/side:[1,2]`, `cube/side\:[1,2]`} {
+		_, err = parseFilter(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	recordsFile, configFile, cleanup := testFiles(t)
+	defer cleanup()
+	out := &bytes.Buffer{}
+	require.Nil(t, run([]string{"-records", recordsFile, "-config", configFile, "--filter", "shaft (screwthread)/pitch:[2,3)"}, out))
+	results := struct {
+		IDs    []string                           `json:"ids"`
+		Facets map[string]*facetengine.FacetGroup `json:"facets"`
+	}{}
+	require.Nil(t, json.Unmarshal(out.Bytes(), &results))
+	require.Equal(t, []string{"2", "3"}, results.IDs)
+	require.Equal(t, 2, results.Facets["shaft (screwthread)"].Count)
+}
+
+func TestRunTable(t *testing.T) {
+	recordsFile, configFile, cleanup := testFiles(t)
+	defer cleanup()
+	out := &bytes.Buffer{}
+	require.Nil(t, run([]string{"-records", recordsFile, "-config", configFile, "-format", "table",
+		"-filter", "shaft (screwthread)/material:steel,brass"}, out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Equal(t, []string{
+		"GROUP                FACET     COUNT  VALUES",
+		"shaft (screwthread)  material  2      brass (1), steel (1)",
+		"shaft (screwthread)  pitch     2      1.5 to 2",
+		"",
+		"2 IDS",
+		"1",
+		"3",
+	}, lines)
+}
+
+func TestRunErrors(t *testing.T) {
+	recordsFile, configFile, cleanup := testFiles(t)
+	defer cleanup()
+	out := &bytes.Buffer{}
+	require.Error(t, run([]string{"-records", recordsFile}, out))
+	require.Error(t, run([]string{"-records", recordsFile, "-config", configFile, "-format", "xml"}, out))
+	require.Error(t, run([]string{"-records", recordsFile, "-config", configFile, "-filter", "pitch"}, out))
+	require.Error(t, run([]string{"-records", configFile, "-config", configFile}, out))
+	require.Error(t, run([]string{"-records", recordsFile, "-config", recordsFile}, out))
+	require.Empty(t, out.String())
+}
@@ -0,0 +1,51 @@
+// Command server runs the facet engine as a local http service for datasets too big for the browser.
+//
+//	server -records records.json -config facet-path.json -addr localhost:8080
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	facetengine "github.com/codeallthethingz/wasm-facet-engine"
+)
+
+func main() {
+	recordsFile := flag.String("records", "", "json file holding an array of records")
+	configFile := flag.String("config", "", "json file holding the facet path")
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	flag.Parse()
+
+	s, err := load(*recordsFile, *configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, s.routes()))
+}
+
+// load create a server from the files, with no records when recordsFile is empty.
+func load(recordsFile string, configFile string) (*server, error) {
+	facetPath := &facetengine.FacetPath{}
+	if configFile != "" {
+		configBytes, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(configBytes, facetPath); err != nil {
+			return nil, facetengine.ToError(err)
+		}
+	}
+	recordsJSON := ""
+	if recordsFile != "" {
+		recordsBytes, err := ioutil.ReadFile(recordsFile)
+		if err != nil {
+			return nil, err
+		}
+		recordsJSON = string(recordsBytes)
+	}
+	return newServer(recordsJSON, facetPath)
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	facetengine "github.com/codeallthethingz/wasm-facet-engine"
+)
+
+// server answers http requests with the same json the wasm bindings resolve to.  Queries sort columns lazily so every
+// request, reads included, holds the lock.
+type server struct {
+	mu     sync.Mutex
+	engine *facetengine.FacetEngine
+}
+
+// initializeRequest is the body of POST /initialize.
+type initializeRequest struct {
+	Config  *facetengine.FacetPath `json:"config"`
+	Records json.RawMessage        `json:"records"`
+}
+
+// newServer create a server with the records loaded using the facet path.
+func newServer(recordsJSON string, facetPath *facetengine.FacetPath) (*server, error) {
+	engine, _, err := facetengine.NewFacetEngine(recordsJSON, facetPath)
+	if err != nil {
+		return nil, err
+	}
+	return &server{engine: engine}, nil
+}
+
+// routes return the handler for every endpoint.
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/initialize", s.handle(http.MethodPost, s.initialize))
+	mux.HandleFunc("/filters", s.handleFilters)
+	mux.HandleFunc("/filters/", s.handle(http.MethodDelete, s.removeFilter))
+	mux.HandleFunc("/query", s.handle(http.MethodGet, s.query))
+	mux.HandleFunc("/facets", s.handle(http.MethodGet, s.facets))
+	return mux
+}
+
+// handle check the method, run the endpoint with the lock held and write its result or error as json.
+func (s *server) handle(method string, endpoint func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeJSON(w, http.StatusMethodNotAllowed, facetengine.NewError(facetengine.ErrInvalidArgument, "%s takes %s", r.URL.Path, method))
+			return
+		}
+		s.mu.Lock()
+		result, err := endpoint(r)
+		s.mu.Unlock()
+		if err != nil {
+			e := facetengine.ToError(err)
+			writeJSON(w, statusOf(e), e)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// handleFilters list the filters on GET, add one on POST and remove them all on DELETE.
+func (s *server) handleFilters(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.handle(http.MethodPost, s.addFilter)(w, r)
+	case http.MethodDelete:
+		s.handle(http.MethodDelete, s.clearFilters)(w, r)
+	default:
+		s.handle(http.MethodGet, s.listFilters)(w, r)
+	}
+}
+
+// initialize replace the records and facet path, resolves to {facets}.  A bad body leaves the previous records loaded.
+func (s *server) initialize(r *http.Request) (interface{}, error) {
+	request := &initializeRequest{}
+	if err := decode(r, request); err != nil {
+		return nil, err
+	}
+	if request.Config == nil {
+		return nil, facetengine.NewError(facetengine.ErrInvalidArgument, "config is required")
+	}
+	engine, facetGroups, err := facetengine.NewFacetEngine(string(request.Records), request.Config)
+	if err != nil {
+		return nil, err
+	}
+	s.engine = engine
+	return map[string]interface{}{"facets": facetGroups}, nil
+}
+
+// addFilter add the expression in the body, resolves to {name}.
+func (s *server) addFilter(r *http.Request) (interface{}, error) {
+	expression := &facetengine.Expression{}
+	if err := decode(r, expression); err != nil {
+		return nil, err
+	}
+	name, err := s.engine.AddExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"name": name}, nil
+}
+
+func (s *server) removeFilter(r *http.Request) (interface{}, error) {
+	name := strings.TrimPrefix(r.URL.Path, "/filters/")
+	if err := s.engine.RemoveFilter(name); err != nil {
+		return nil, err
+	}
+	return map[string]string{"name": name}, nil
+}
+
+func (s *server) clearFilters(r *http.Request) (interface{}, error) {
+	s.engine.ClearFilters()
+	return s.engine.ListFilters(), nil
+}
+
+func (s *server) listFilters(r *http.Request) (interface{}, error) {
+	return s.engine.ListFilters(), nil
+}
+
+// query resolves to {ids, facets} for the current filters.
+func (s *server) query(r *http.Request) (interface{}, error) {
+	ids, facetGroups, err := s.engine.Query()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"ids": ids, "facets": facetGroups}, nil
+}
+
+// facets is query without the ids, which can be most of the response for a large dataset.
+func (s *server) facets(r *http.Request) (interface{}, error) {
+	_, facetGroups, err := s.engine.Query()
+	if err != nil {
+		return nil, err
+	}
+	return facetGroups, nil
+}
+
+// decode read the json body in to v.
+func decode(r *http.Request, v interface{}) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return facetengine.NewError(facetengine.ErrInvalidArgument, "unable to read body: %v", err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return facetengine.ToError(err)
+	}
+	return nil
+}
+
+// statusOf pick the http status for the error's code.
+func statusOf(e *facetengine.Error) int {
+	switch e.Code {
+	case facetengine.ErrInternal:
+		return http.StatusInternalServerError
+	case facetengine.ErrUnknownFilter, facetengine.ErrUnknownRecord, facetengine.ErrUnknownFacet:
+		return http.StatusNotFound
+	case facetengine.ErrDuplicateRecord:
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		body = []byte(`{"code":"internal","message":"unable to encode response"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
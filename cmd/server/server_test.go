@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	facetengine "github.com/codeallthethingz/wasm-facet-engine"
+	"github.com/stretchr/testify/require"
+)
+
+var readmeConfig = `{"arrayDotNotation":"measurements","nameFieldDotNotation":"measurementName","nameMetaDotNotation":"metrics.metricName","valueMapDotNotation":"metrics.measurements"}`
+
+var readmeRecords = `[
+	{"id": "record 1", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "10"}}}]},
+	{"id": "record 2", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "20"}}}]}
+]`
+
+func testServer(t *testing.T) *httptest.Server {
+	facetPath := &facetengine.FacetPath{}
+	require.Nil(t, json.Unmarshal([]byte(readmeConfig), facetPath))
+	s, err := newServer(readmeRecords, facetPath)
+	require.Nil(t, err)
+	return httptest.NewServer(s.routes())
+}
+
+func request(t *testing.T, ts *httptest.Server, method string, path string, body string, response interface{}) int {
+	r, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	require.Nil(t, err)
+	resp, err := http.DefaultClient.Do(r)
+	require.Nil(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	if response != nil {
+		require.Nil(t, json.NewDecoder(resp.Body).Decode(response))
+	}
+	return resp.StatusCode
+}
+
+type queryResponse struct {
+	IDs    []string                           `json:"ids"`
+	Facets map[string]*facetengine.FacetGroup `json:"facets"`
+}
+
+func TestQuery(t *testing.T) {
+	ts := testServer(t)
+	defer ts.Close()
+	response := &queryResponse{}
+	require.Equal(t, http.StatusOK, request(t, ts, http.MethodGet, "/query", "", response))
+	require.ElementsMatch(t, []string{"record 1", "record 2"}, response.IDs)
+	require.Equal(t, 2, response.Facets["area (cube)"].Count)
+	require.Equal(t, map[string]int{"10": 1, "20": 1}, response.Facets["area (cube)"].Facets["side"].Counts)
+}
+
+func TestFilters(t *testing.T) {
+	ts := testServer(t)
+	defer ts.Close()
+	added := map[string]string{}
+	filter := `{"facetGroupName": "area (cube)", "facetName": "side", "inclusiveMin": true, "min": 8, "inclusiveMax": false, "max": 12}`
+	require.Equal(t, http.StatusOK, request(t, ts, http.MethodPost, "/filters", filter, &added))
+	require.Equal(t, "filter-1", added["name"])
+
+	filters := []map[string]interface{}{}
+	require.Equal(t, http.StatusOK, request(t, ts, http.MethodGet, "/filters", "", &filters))
+	require.Equal(t, 1, len(filters))
+	require.Equal(t, "filter-1", filters[0]["name"])
+
+	response := &queryResponse{}
+	require.Equal(t, http.StatusOK, request(t, ts, http.MethodGet, "/query", "", response))
+	require.Equal(t, []string{"record 1"}, response.IDs)
+
+	facets := map[string]*facetengine.FacetGroup{}
+	require.Equal(t, http.StatusOK, request(t, ts, http.MethodGet, "/facets", "", &facets))
+	require.Equal(t, 1, facets["area (cube)"].Count)
+
+	require.Equal(t, http.StatusOK, request(t, ts, http.MethodDelete, "/filters/filter-1", "", nil))
+	e := &facetengine.Error{}
+	require.Equal(t, http.StatusNotFound, request(t, ts, http.MethodDelete, "/filters/filter-1", "", e))
+	require.Equal(t, facetengine.ErrUnknownFilter, e.Code)
+
+	require.Equal(t, http.StatusOK, request(t, ts, http.MethodPost, "/filters", filter, nil))
+	require.Equal(t, http.StatusOK, request(t, ts, http.MethodDelete, "/filters", "", nil))
+	require.Equal(t, http.StatusOK, request(t, ts, http.MethodGet, "/query", "", response))
+	require.Equal(t, 2, len(response.IDs))
+}
+
+func TestBadFilter(t *testing.T) {
+	ts := testServer(t)
+	defer ts.Close()
+	e := &facetengine.Error{}
+	require.Equal(t, http.StatusBadRequest, request(t, ts, http.MethodPost, "/filters", `{"and": [`, e))
+	require.Equal(t, facetengine.ErrInvalidJSON, e.Code)
+	require.Equal(t, http.StatusBadRequest, request(t, ts, http.MethodPost, "/filters", `{"facetGroupName": "area (cube)"}`, e))
+	require.Equal(t, facetengine.ErrInvalidFilter, e.Code)
+}
+
+func TestInitialize(t *testing.T) {
+	ts := testServer(t)
+	defer ts.Close()
+	response := map[string]map[string]*facetengine.FacetGroup{}
+	body := `{"config": ` + readmeConfig + `, "records": [{"id": "record 3", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "30"}}}]}]}`
+	require.Equal(t, http.StatusOK, request(t, ts, http.MethodPost, "/initialize", body, &response))
+	require.Equal(t, 1, response["facets"]["area (cube)"].Count)
+
+	e := &facetengine.Error{}
+	require.Equal(t, http.StatusBadRequest, request(t, ts, http.MethodPost, "/initialize", `{"records": []}`, e))
+	require.Equal(t, facetengine.ErrInvalidArgument, e.Code)
+	require.Equal(t, http.StatusBadRequest, request(t, ts, http.MethodPost, "/initialize", `{"config": {}, "records": [{}]}`, e))
+	require.Equal(t, facetengine.ErrMissingID, e.Code)
+
+	query := &queryResponse{}
+	require.Equal(t, http.StatusOK, request(t, ts, http.MethodGet, "/query", "", query))
+	require.Equal(t, []string{"record 3"}, query.IDs)
+}
+
+func TestWrongMethod(t *testing.T) {
+	ts := testServer(t)
+	defer ts.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, request(t, ts, http.MethodPost, "/query", "", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, request(t, ts, http.MethodGet, "/initialize", "", nil))
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "facet-engine")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	recordsFile, configFile := filepath.Join(dir, "records.json"), filepath.Join(dir, "config.json")
+	require.Nil(t, ioutil.WriteFile(recordsFile, []byte(readmeRecords), 0600))
+	require.Nil(t, ioutil.WriteFile(configFile, []byte(readmeConfig), 0600))
+
+	s, err := load(recordsFile, configFile)
+	require.Nil(t, err)
+	ids, _, err := s.engine.Query()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(ids))
+
+	_, err = load(filepath.Join(dir, "missing.json"), configFile)
+	require.Error(t, err)
+	_, err = load(configFile, recordsFile)
+	require.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package main
+
+import facetengine "github.com/codeallthethingz/wasm-facet-engine"
+
+var readmeFacetPath = &facetengine.FacetPath{
+	ArrayDotNotation:     "measurements",
+	NameFieldDotNotation: "measurementName",
+	NameMetaDotNotation:  "metrics.metricName",
+	ValueMapDotNotation:  "metrics.measurements",
+}
+
+var readmeExample = `
+[
+  {
+    "id": "record 1",
+    "measurements": [
+      {
+        "measurementName": "area",
+        "metrics": {
+          "metricName": "cube",
+          "measurements": {
+            "side": "10"
+          }
+        }
+      }
+    ]
+  },
+  {
+    "id": "record 2",
+    "measurements": [
+      {
+        "measurementName": "area",
+        "metrics": {
+          "metricName": "cube",
+          "measurements": {
+            "side": "20"
+          }
+        }
+      }
+    ]
+  }
+]
+`
+
+var categoricalFacetPath = &facetengine.FacetPath{
+	ArrayDotNotation:     "bounds",
+	NameFieldDotNotation: "name",
+	NameMetaDotNotation:  "boundingType.name",
+	ValueMapDotNotation:  "boundingType.measurements",
+	CategoricalFacets:    []string{"Material"},
+}
+
+var categoricalExample = `[
+	{"id": "1", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "1.5", "material": "steel"}}}]},
+	{"id": "2", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "2", "material": "stainless steel"}}}]},
+	{"id": "3", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "2", "material": "brass"}}}]},
+	{"id": "4", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "1"}}}]}
+]`
@@ -0,0 +1,709 @@
+package main
+
+import (
+	"encoding/json"
+
+	facetengine "github.com/codeallthethingz/wasm-facet-engine"
+	"github.com/gopherjs/gopherwasm/js"
+)
+
+// facetEngine starts empty so bindings called before initializeObjects report not_initialized rather than crash.
+var facetEngine, _, _ = facetengine.NewFacetEngine("", nil)
+
+// errorCallback receives every error reported by a binding, see JSOnError.
+var errorCallback *js.Value
+
+func main() {
+	// create empty channel so main doesn't exit when it's wasm-ed.
+	c := make(chan struct{}, 0)
+	registerCallbacks()
+	<-c
+}
+
+func registerCallbacks() {
+	js.Global().Get("facetEngine").Set("onError", js.NewCallback(guard(1, JSOnError)))
+	js.Global().Get("facetEngine").Set("initializeObjects", bind(2, JSInitializeObjects))
+	js.Global().Get("facetEngine").Set("addRecords", bind(1, JSAddRecords))
+	js.Global().Get("facetEngine").Set("updateRecord", bind(1, JSUpdateRecord))
+	js.Global().Get("facetEngine").Set("deleteRecords", bind(1, JSDeleteRecords))
+	js.Global().Get("facetEngine").Set("query", bind(0, JSQuery))
+	js.Global().Get("facetEngine").Set("addFilter", bind(6, JSAddFilter))
+	js.Global().Get("facetEngine").Set("addValueFilter", bind(4, JSAddValueFilter))
+	js.Global().Get("facetEngine").Set("addExpression", bind(1, JSAddExpression))
+	js.Global().Get("facetEngine").Set("addOrGroup", bind(1, JSAddOrGroup))
+	js.Global().Get("facetEngine").Set("addNotFilter", bind(6, JSAddNotFilter))
+	js.Global().Get("facetEngine").Set("removeFilter", bind(1, JSRemoveFilter))
+	js.Global().Get("facetEngine").Set("updateFilter", bind(7, JSUpdateFilter))
+	js.Global().Get("facetEngine").Set("updateExpression", bind(2, JSUpdateExpression))
+	js.Global().Get("facetEngine").Set("listFilters", bind(0, JSListFilters))
+	js.Global().Get("facetEngine").Set("clearFilters", bind(0, JSClearFilters))
+	js.Global().Get("facetEngine").Set("setDisjunctive", bind(1, JSSetDisjunctive))
+	js.Global().Get("facetEngine").Set("histogram", bind(3, JSHistogram))
+	js.Global().Get("facetEngine").Set("statistics", bind(3, JSStatistics))
+	js.Global().Get("facetEngine").Set("setPercentiles", bind(1, JSSetPercentiles))
+	js.Global().Get("facetEngine").Set("initializeRecords", bind(2, JSInitializeRecords))
+	js.Global().Get("facetEngine").Set("addRecordObjects", bind(1, JSAddRecordObjects))
+	js.Global().Get("facetEngine").Set("updateRecordObject", bind(1, JSUpdateRecordObject))
+	js.Global().Get("facetEngine").Set("deleteOrdinals", bind(1, JSDeleteOrdinals))
+	js.Global().Get("facetEngine").Set("queryObjects", bind(0, JSQueryObjects))
+	js.Global().Get("facetEngine").Set("snapshot", bind(0, JSSnapshot))
+	js.Global().Get("facetEngine").Set("restoreSnapshot", bind(1, JSRestoreSnapshot))
+	js.Global().Get("facetEngine").Set("startIngest", bind(1, JSStartIngest))
+	js.Global().Get("facetEngine").Set("ingestChunk", bind(1, JSIngestChunk))
+	js.Global().Get("facetEngine").Set("finishIngest", bind(0, JSFinishIngest))
+	js.Global().Get("facetEngine").Set("record", bind(1, JSRecord))
+	js.Global().Get("facetEngine").Set("memoryUsage", bind(0, JSMemoryUsage))
+}
+
+// bind expose a binding as a javascript function returning a Promise.  The Promise resolves to the binding's result or
+// rejects with an Error carrying the code, recordId and path of the failure.  Callbacks passed in the older positional
+// style are still invoked by the bindings themselves.
+func bind(minArgs int, binding func([]js.Value) (interface{}, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		promise := js.Global().Get("Promise")
+		result, err := call(minArgs, binding, args)
+		if err != nil {
+			if errorCallback != nil {
+				reportError(err)
+			}
+			return promise.Call("reject", toJSError(err))
+		}
+		return promise.Call("resolve", result)
+	})
+}
+
+// call run the binding, turning too few arguments or a panic in to an error rather than letting it stop the go
+// runtime.
+func call(minArgs int, binding func([]js.Value) (interface{}, error), args []js.Value) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = facetengine.NewError(facetengine.ErrInternal, "%v", r)
+		}
+	}()
+	if len(args) < minArgs {
+		return nil, facetengine.NewError(facetengine.ErrInvalidArgument, "expected at least %d arguments but got %d", minArgs, len(args))
+	}
+	return binding(args)
+}
+
+// guard is call for bindings that don't return a Promise, errors go to reportError.
+func guard(minArgs int, binding func([]js.Value) error) func([]js.Value) {
+	return func(args []js.Value) {
+		_, err := call(minArgs, func(args []js.Value) (interface{}, error) {
+			return nil, binding(args)
+		}, args)
+		if err != nil {
+			reportError(err)
+		}
+	}
+}
+
+// toJSError convert the error in to a javascript Error with the fields of Error.
+func toJSError(err error) js.Value {
+	e := facetengine.ToError(err)
+	jsError := js.Global().Get("Error").New(e.Message)
+	jsError.Set("code", e.Code)
+	if e.RecordID != "" {
+		jsError.Set("recordId", e.RecordID)
+	}
+	if e.Path != "" {
+		jsError.Set("path", e.Path)
+	}
+	return jsError
+}
+
+// parseJSON turn a string produced by json.Marshal in to a javascript value.
+func parseJSON(jsonString string) js.Value {
+	return js.Global().Get("JSON").Call("parse", jsonString)
+}
+
+// reportError send the stringified error to the callback registered with onError, or the console when there isn't one.
+func reportError(err error) {
+	errorBytes, marshalErr := json.Marshal(facetengine.ToError(err))
+	if marshalErr != nil {
+		errorBytes = []byte(`{"code":"internal","message":"unable to report error"}`)
+	}
+	if errorCallback != nil {
+		errorCallback.Invoke(string(errorBytes))
+		return
+	}
+	js.Global().Get("console").Call("error", string(errorBytes))
+}
+
+// JSOnError register the callback that gets the stringified error whenever a binding fails
+func JSOnError(args []js.Value) error {
+	if args[0].Type() != js.TypeFunction {
+		return facetengine.NewError(facetengine.ErrInvalidArgument, "onError takes a callback")
+	}
+	callback := args[0]
+	errorCallback = &callback
+	return nil
+}
+
+// JSClearFilters remove all the filters
+//noinspection GoUnusedParameter
+func JSClearFilters(args []js.Value) (interface{}, error) {
+	facetEngine.ClearFilters()
+	return js.Undefined(), nil
+}
+
+// JSSetDisjunctive turn on or off computing each facet without the filters on that facet
+func JSSetDisjunctive(args []js.Value) (interface{}, error) {
+	facetEngine.SetDisjunctive(args[0].Bool())
+	return js.Undefined(), nil
+}
+
+// JSAddFilter adds a filter to the query object, resolves to the filter's name which the optional callback also gets
+func JSAddFilter(args []js.Value) (interface{}, error) {
+	facetGroupName := args[0].String()
+	facetName := args[1].String()
+	inclusiveMin := args[2].Bool()
+	min := args[3].Float()
+	inclusiveMax := args[4].Bool()
+	max := args[5].Float()
+	name, err := addFilter(facetGroupName, facetName, inclusiveMin, min, inclusiveMax, max)
+	if err != nil {
+		return nil, err
+	}
+	invokeOptional(args, 6, name)
+	return name, nil
+}
+
+func addFilter(facetGroupName string, facetName string, inclusiveMin bool, min float64, inclusiveMax bool, max float64) (string, error) {
+	minRange, maxRange := toRanges(inclusiveMin, min, inclusiveMax, max)
+	return facetEngine.AddFilter(facetGroupName, facetName, minRange, maxRange)
+}
+
+func toRanges(inclusiveMin bool, min float64, inclusiveMax bool, max float64) (facetengine.Range, facetengine.Range) {
+	minRange := facetengine.Exclusive(min)
+	maxRange := facetengine.Exclusive(max)
+	if inclusiveMin {
+		minRange = facetengine.Inclusive(min)
+	}
+	if inclusiveMax {
+		maxRange = facetengine.Inclusive(max)
+	}
+	return minRange, maxRange
+}
+
+// JSAddValueFilter adds an equals, in, notIn or prefix filter on a categorical facet, resolves to the filter's name
+// which the optional callback also gets
+func JSAddValueFilter(args []js.Value) (interface{}, error) {
+	name, err := addValueFilter(args[0].String(), args[1].String(), args[2].String(), args[3].String())
+	if err != nil {
+		return nil, err
+	}
+	invokeOptional(args, 4, name)
+	return name, nil
+}
+
+func addValueFilter(facetGroupName string, facetName string, match string, valuesJSON string) (string, error) {
+	var values []string
+	err := json.Unmarshal([]byte(valuesJSON), &values)
+	if err != nil {
+		return "", err
+	}
+	expression, err := facetengine.NewValueFilter(facetGroupName, facetName, match, values...)
+	if err != nil {
+		return "", err
+	}
+	return facetEngine.AddExpression(expression)
+}
+
+// invokeOptional call the callback at position i if the caller supplied one.
+func invokeOptional(args []js.Value, i int, value interface{}) {
+	if len(args) > i && args[i].Type() == js.TypeFunction {
+		args[i].Invoke(value)
+	}
+}
+
+// JSAddExpression adds a tree of and / or / not filters to the query object, resolves to the filter's name which the
+// optional callback also gets
+func JSAddExpression(args []js.Value) (interface{}, error) {
+	name, err := addExpression(args[0].String())
+	if err != nil {
+		return nil, err
+	}
+	invokeOptional(args, 1, name)
+	return name, nil
+}
+
+func addExpression(expressionJSON string) (string, error) {
+	expression, err := parseExpression(expressionJSON)
+	if err != nil {
+		return "", err
+	}
+	return facetEngine.AddExpression(expression)
+}
+
+func parseExpression(expressionJSON string) (*facetengine.Expression, error) {
+	expression := &facetengine.Expression{}
+	err := json.Unmarshal([]byte(expressionJSON), expression)
+	if err != nil {
+		return nil, err
+	}
+	return expression, nil
+}
+
+// JSAddOrGroup adds a list of filters where records have to match at least one of them, resolves to the filter's name
+// which the optional callback also gets
+func JSAddOrGroup(args []js.Value) (interface{}, error) {
+	name, err := addOrGroup(args[0].String())
+	if err != nil {
+		return nil, err
+	}
+	invokeOptional(args, 1, name)
+	return name, nil
+}
+
+func addOrGroup(expressionsJSON string) (string, error) {
+	var expressions []*facetengine.Expression
+	err := json.Unmarshal([]byte(expressionsJSON), &expressions)
+	if err != nil {
+		return "", err
+	}
+	return facetEngine.AddOrGroup(expressions...)
+}
+
+// JSAddNotFilter adds a filter that records must not match, resolves to the filter's name which the optional callback
+// also gets
+func JSAddNotFilter(args []js.Value) (interface{}, error) {
+	facetGroupName := args[0].String()
+	facetName := args[1].String()
+	inclusiveMin := args[2].Bool()
+	min := args[3].Float()
+	inclusiveMax := args[4].Bool()
+	max := args[5].Float()
+	name, err := addNotFilter(facetGroupName, facetName, inclusiveMin, min, inclusiveMax, max)
+	if err != nil {
+		return nil, err
+	}
+	invokeOptional(args, 6, name)
+	return name, nil
+}
+
+func addNotFilter(facetGroupName string, facetName string, inclusiveMin bool, min float64, inclusiveMax bool, max float64) (string, error) {
+	minRange, maxRange := toRanges(inclusiveMin, min, inclusiveMax, max)
+	return facetEngine.AddNot(facetengine.NewFilter(facetGroupName, facetName, minRange, maxRange))
+}
+
+// JSRemoveFilter remove a filter by name
+func JSRemoveFilter(args []js.Value) (interface{}, error) {
+	return js.Undefined(), facetEngine.RemoveFilter(args[0].String())
+}
+
+// JSUpdateFilter replace the range of a named filter
+func JSUpdateFilter(args []js.Value) (interface{}, error) {
+	name := args[0].String()
+	facetGroupName := args[1].String()
+	facetName := args[2].String()
+	inclusiveMin := args[3].Bool()
+	min := args[4].Float()
+	inclusiveMax := args[5].Bool()
+	max := args[6].Float()
+	return js.Undefined(), updateFilter(name, facetGroupName, facetName, inclusiveMin, min, inclusiveMax, max)
+}
+
+func updateFilter(name string, facetGroupName string, facetName string, inclusiveMin bool, min float64, inclusiveMax bool, max float64) error {
+	minRange, maxRange := toRanges(inclusiveMin, min, inclusiveMax, max)
+	return facetEngine.UpdateFilter(name, facetengine.NewFilter(facetGroupName, facetName, minRange, maxRange))
+}
+
+// JSUpdateExpression replace a named filter with a tree of and / or / not filters
+func JSUpdateExpression(args []js.Value) (interface{}, error) {
+	return js.Undefined(), updateExpression(args[0].String(), args[1].String())
+}
+
+func updateExpression(name string, expressionJSON string) error {
+	expression, err := parseExpression(expressionJSON)
+	if err != nil {
+		return err
+	}
+	return facetEngine.UpdateFilter(name, expression)
+}
+
+// JSListFilters resolve to the list of named filters, the optional callback gets it stringified
+func JSListFilters(args []js.Value) (interface{}, error) {
+	filters, err := listFilters()
+	if err != nil {
+		return nil, err
+	}
+	invokeOptional(args, 0, filters)
+	return parseJSON(filters), nil
+}
+
+func listFilters() (string, error) {
+	filtersBytes, err := json.Marshal(facetEngine.ListFilters())
+	if err != nil {
+		return "", err
+	}
+	return string(filtersBytes), nil
+}
+
+// JSQuery WASM interface to query the facet groups, resolves to {ids, facets}.  The optional callbacks get the
+// stringified ids and facets
+func JSQuery(args []js.Value) (interface{}, error) {
+	ids, facetGroups, err := query()
+	if err != nil {
+		return nil, err
+	}
+	invokeOptional(args, 0, ids)
+	invokeOptional(args, 1, facetGroups)
+	return map[string]interface{}{"ids": parseJSON(ids), "facets": parseJSON(facetGroups)}, nil
+}
+
+func query() (string, string, error) {
+	ids, facetGroups, err := facetEngine.Query()
+	if err != nil {
+		return "", "", err
+	}
+	idsBytes, err := json.Marshal(ids)
+	if err != nil {
+		return "", "", err
+	}
+	facetGroupBytes, err := json.Marshal(facetGroups)
+	if err != nil {
+		return "", "", err
+	}
+	return string(idsBytes), string(facetGroupBytes), nil
+}
+
+// JSHistogram resolve to the buckets of a facet's values for the current filters, the optional callback gets them
+// stringified
+func JSHistogram(args []js.Value) (interface{}, error) {
+	buckets, err := histogram(args[0].String(), args[1].String(), args[2].String())
+	if err != nil {
+		return nil, err
+	}
+	invokeOptional(args, 3, buckets)
+	return parseJSON(buckets), nil
+}
+
+func histogram(facetGroupName string, facetName string, bucketsJSON string) (string, error) {
+	buckets, err := facetengine.ParseBuckets(bucketsJSON)
+	if err != nil {
+		return "", err
+	}
+	results, err := facetEngine.Histogram(facetGroupName, facetName, buckets)
+	if err != nil {
+		return "", err
+	}
+	resultsBytes, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+	return string(resultsBytes), nil
+}
+
+// JSStatistics resolve to the statistics of a facet's values for the current filters, the optional callback gets them
+// stringified
+func JSStatistics(args []js.Value) (interface{}, error) {
+	results, err := statistics(args[0].String(), args[1].String(), args[2].String())
+	if err != nil {
+		return nil, err
+	}
+	invokeOptional(args, 3, results)
+	return parseJSON(results), nil
+}
+
+func statistics(facetGroupName string, facetName string, percentilesJSON string) (string, error) {
+	var percentiles []float64
+	err := json.Unmarshal([]byte(percentilesJSON), &percentiles)
+	if err != nil {
+		return "", err
+	}
+	results, err := facetEngine.Statistics(facetGroupName, facetName, percentiles...)
+	if err != nil {
+		return "", err
+	}
+	resultsBytes, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+	return string(resultsBytes), nil
+}
+
+// JSSetPercentiles choose which percentiles are in the statistics of each facet returned from query
+func JSSetPercentiles(args []js.Value) (interface{}, error) {
+	return js.Undefined(), setPercentiles(args[0].String())
+}
+
+func setPercentiles(percentilesJSON string) error {
+	var percentiles []float64
+	err := json.Unmarshal([]byte(percentilesJSON), &percentiles)
+	if err != nil {
+		return err
+	}
+	return facetEngine.SetPercentiles(percentiles...)
+}
+
+// JSAddRecords add a stringified array of new objects without reinitializing
+func JSAddRecords(args []js.Value) (interface{}, error) {
+	return js.Undefined(), facetEngine.AddRecords(args[0].String())
+}
+
+// JSUpdateRecord replace the record with the same id as the stringified object
+func JSUpdateRecord(args []js.Value) (interface{}, error) {
+	return js.Undefined(), facetEngine.UpdateRecord(args[0].String())
+}
+
+// JSDeleteRecords remove the records in a stringified array of ids
+func JSDeleteRecords(args []js.Value) (interface{}, error) {
+	return js.Undefined(), deleteRecords(args[0].String())
+}
+
+func deleteRecords(idsJSON string) error {
+	var ids []string
+	err := json.Unmarshal([]byte(idsJSON), &ids)
+	if err != nil {
+		return err
+	}
+	return facetEngine.DeleteRecords(ids)
+}
+
+// JSInitializeObjects wasm interface to take the data and parse out the facets, resolves to {facets}.  The optional
+// callback gets the stringified facets
+func JSInitializeObjects(args []js.Value) (interface{}, error) {
+	configString := args[0].String()
+	dataJSON := args[1].String()
+	facetGroupsString, err := initializeObjects(configString, dataJSON)
+	if err != nil {
+		return nil, err
+	}
+	invokeOptional(args, 2, facetGroupsString)
+	return map[string]interface{}{"facets": parseJSON(facetGroupsString)}, nil
+}
+
+func initializeObjects(configString string, dataJSON string) (string, error) {
+	facetPath := &facetengine.FacetPath{}
+	err := json.Unmarshal([]byte(configString), facetPath)
+	if err != nil {
+		return "", err
+	}
+	// only replace the engine when the new data is good so a bad call leaves the previous data queryable.
+	engine, facetGroups, err := facetengine.NewFacetEngine(dataJSON, facetPath)
+	if err != nil {
+		return "", err
+	}
+	facetGroupsBytes, err := json.Marshal(facetGroups)
+	if err != nil {
+		return "", err
+	}
+	facetEngine = engine
+	return string(facetGroupsBytes), nil
+}
+
+// JSInitializeRecords take the configuration and records as javascript objects rather than strings, resolves to
+// {facets}
+func JSInitializeRecords(args []js.Value) (interface{}, error) {
+	facetPath, err := facetPathFromJSValue(args[0])
+	if err != nil {
+		return nil, err
+	}
+	genericObjects, err := objectsFromJSValue(args[1])
+	if err != nil {
+		return nil, err
+	}
+	facetGroups, err := initializeRecords(facetPath, genericObjects)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"facets": facetGroupsToNative(facetGroups)}, nil
+}
+
+func initializeRecords(facetPath *facetengine.FacetPath, genericObjects []map[string]interface{}) (map[string]*facetengine.FacetGroup, error) {
+	engine, _, _ := facetengine.NewFacetEngine("", nil)
+	facetGroups, err := engine.InitializeObjects(genericObjects, facetPath)
+	if err != nil {
+		return nil, err
+	}
+	facetEngine = engine
+	return facetGroups, nil
+}
+
+// JSAddRecordObjects add an array of new objects without reinitializing
+func JSAddRecordObjects(args []js.Value) (interface{}, error) {
+	genericObjects, err := objectsFromJSValue(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return js.Undefined(), facetEngine.AddObjects(genericObjects)
+}
+
+// JSUpdateRecordObject replace the record with the same id as the object
+func JSUpdateRecordObject(args []js.Value) (interface{}, error) {
+	genericObject, ok := fromJSValue(args[0]).(map[string]interface{})
+	if !ok {
+		return nil, facetengine.NewError(facetengine.ErrInvalidArgument, "expected an object")
+	}
+	return js.Undefined(), facetEngine.UpdateObject(genericObject)
+}
+
+// JSDeleteOrdinals remove the records with the ordinals in a typed array
+func JSDeleteOrdinals(args []js.Value) (interface{}, error) {
+	return js.Undefined(), deleteOrdinals(ordinalsFromJSValue(args[0]))
+}
+
+func deleteOrdinals(ordinals []uint32) error {
+	ids, err := facetEngine.IDs(ordinals)
+	if err != nil {
+		return err
+	}
+	return facetEngine.DeleteRecords(ids)
+}
+
+// JSQueryObjects query the facet groups, resolves to {ids, ordinals, facets} built as javascript objects with the
+// ordinals in a Uint32Array
+func JSQueryObjects(args []js.Value) (interface{}, error) {
+	ids, ordinals, facetGroups, err := queryOrdinals()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"ids":      stringsToNative(ids),
+		"ordinals": ordinalsToJSValue(ordinals),
+		"facets":   facetGroupsToNative(facetGroups),
+	}, nil
+}
+
+func queryOrdinals() ([]string, []uint32, map[string]*facetengine.FacetGroup, error) {
+	ids, facetGroups, err := facetEngine.Query()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ordinals, err := facetEngine.Ordinals(ids)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return ids, ordinals, facetGroups, nil
+}
+
+// JSSnapshot encode the index, resolves to a Uint8Array that can be stored and passed to restoreSnapshot
+//noinspection GoUnusedParameter
+func JSSnapshot(args []js.Value) (interface{}, error) {
+	return bytesToJSValue(facetEngine.Snapshot()), nil
+}
+
+// JSRestoreSnapshot replace the index with one from snapshot, resolves to {facets}
+func JSRestoreSnapshot(args []js.Value) (interface{}, error) {
+	facetGroups, err := restoreSnapshot(bytesFromJSValue(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"facets": facetGroupsToNative(facetGroups)}, nil
+}
+
+func restoreSnapshot(snapshot []byte) (map[string]*facetengine.FacetGroup, error) {
+	// only replace the engine when the snapshot is good so a bad call leaves the previous data queryable.
+	engine, _, _ := facetengine.NewFacetEngine("", nil)
+	facetGroups, err := engine.RestoreSnapshot(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	facetEngine = engine
+	return facetGroups, nil
+}
+
+// ingester is the stream started by startIngest, its engine replaces facetEngine when finishIngest is called.
+var ingester *facetengine.Ingester
+var ingestEngine *facetengine.FacetEngine
+
+// JSStartIngest begin streaming records for the stringified configuration.  The optional callback is sent the number
+// of records indexed after each chunk
+func JSStartIngest(args []js.Value) (interface{}, error) {
+	var progress func(records int)
+	if len(args) > 1 && args[1].Type() == js.TypeFunction {
+		callback := args[1]
+		progress = func(records int) {
+			callback.Invoke(records)
+		}
+	}
+	return js.Undefined(), startIngest(args[0].String(), progress)
+}
+
+func startIngest(configString string, progress func(records int)) error {
+	facetPath := &facetengine.FacetPath{}
+	if err := json.Unmarshal([]byte(configString), facetPath); err != nil {
+		return facetengine.ToError(err)
+	}
+	engine, _, err := facetengine.NewFacetEngine("", facetPath)
+	if err != nil {
+		return err
+	}
+	streaming, err := engine.NewIngester(progress)
+	if err != nil {
+		return err
+	}
+	ingester, ingestEngine = streaming, engine
+	return nil
+}
+
+// JSIngestChunk index the records in a chunk of newline delimited json, a string or a Uint8Array of utf-8, resolves to
+// the number of records indexed so far
+func JSIngestChunk(args []js.Value) (interface{}, error) {
+	if args[0].Type() == js.TypeString {
+		return ingestChunk([]byte(args[0].String()))
+	}
+	return ingestChunk(bytesFromJSValue(args[0]))
+}
+
+func ingestChunk(chunk []byte) (int, error) {
+	if ingester == nil {
+		return 0, facetengine.NewError(facetengine.ErrNotInitialized, "must startIngest before sending chunks")
+	}
+	if _, err := ingester.Write(chunk); err != nil {
+		return 0, err
+	}
+	return ingester.Records(), nil
+}
+
+// JSFinishIngest index the last line and replace the records with the streamed ones, resolves to {facets}
+//noinspection GoUnusedParameter
+func JSFinishIngest(args []js.Value) (interface{}, error) {
+	facetGroups, err := finishIngest()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"facets": facetGroupsToNative(facetGroups)}, nil
+}
+
+func finishIngest() (map[string]*facetengine.FacetGroup, error) {
+	if ingester == nil {
+		return nil, facetengine.NewError(facetengine.ErrNotInitialized, "must startIngest before finishing")
+	}
+	// the stream is over whether or not it worked, a failed one leaves the previous records queryable.
+	streaming, engine := ingester, ingestEngine
+	ingester, ingestEngine = nil, nil
+	if err := streaming.Close(); err != nil {
+		return nil, err
+	}
+	facetEngine = engine
+	return facetEngine.GetFacets()
+}
+
+// JSRecord resolves to the object a record was indexed from, the configuration must set retainRecords
+func JSRecord(args []js.Value) (interface{}, error) {
+	genericObject, err := facetEngine.Record(args[0].String())
+	if err != nil {
+		return nil, err
+	}
+	return genericObject, nil
+}
+
+// JSMemoryUsage resolves to an estimate of the memory held by the ids, the index and any retained records
+//noinspection GoUnusedParameter
+func JSMemoryUsage(args []js.Value) (interface{}, error) {
+	usage, err := memoryUsage()
+	if err != nil {
+		return nil, err
+	}
+	return parseJSON(usage), nil
+}
+
+func memoryUsage() (string, error) {
+	usageBytes, err := json.Marshal(facetEngine.MemoryUsage())
+	if err != nil {
+		return "", err
+	}
+	return string(usageBytes), nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	facetengine "github.com/codeallthethingz/wasm-facet-engine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitialize(t *testing.T) {
+	facetGroups, err := initializeObjects("{}", "[]")
+	if err != nil {
+		panic(err)
+	}
+	require.Equal(t, "{}", facetGroups)
+}
+func TestQuery(t *testing.T) {
+	_, _ = initializeObjects("{}", "[]")
+	ids, facetGroups, err := query()
+	require.Nil(t, err)
+	require.Equal(t, "[]", ids)
+	require.Equal(t, "{}", facetGroups)
+}
+func TestFilter(t *testing.T) {
+	_, _ = initializeObjects("{}", "[]")
+	name, err := addFilter("facetGroupName", "facetName", true, 0, true, 10)
+	require.Nil(t, err)
+	require.Equal(t, "filter-1", name)
+}
+func TestFilterError(t *testing.T) {
+	_, _ = initializeObjects("{}", "[]")
+	_, err := addFilter(" ", "facetName", true, 0, true, 10)
+	require.Error(t, err)
+	_, err = addFilter("group", " ", true, 0, true, 10)
+	require.Error(t, err)
+}
+func TestAddValueFilter(t *testing.T) {
+	_, _ = initializeObjects("{}", "[]")
+	_, err := addValueFilter("group", "facet", "in", `["a","b"]`)
+	require.Nil(t, err)
+	_, err = addValueFilter("group", "facet", "equals", `["a","b"]`)
+	require.Error(t, err)
+	_, err = addValueFilter("group", "facet", "between", `["a"]`)
+	require.Error(t, err)
+	_, err = addValueFilter("group", "facet", "prefix", `NOTJSON`)
+	require.Error(t, err)
+}
+func TestAddExpression(t *testing.T) {
+	_, _ = initializeObjects("{}", "[]")
+	_, err := addExpression(`{"or":[{"facetGroupName":"group","facetName":"facet","min":1,"max":2},{"not":{"facetGroupName":"group","facetName":"other","min":1,"max":2}}]}`)
+	require.Nil(t, err)
+	_, err = addExpression(`{"and":[]}`)
+	require.Error(t, err)
+	_, err = addExpression(`NOTJSON`)
+	require.Error(t, err)
+}
+func TestAddOrGroup(t *testing.T) {
+	_, _ = initializeObjects("{}", "[]")
+	_, err := addOrGroup(`[{"facetGroupName":"group","facetName":"facet","min":1,"max":2}]`)
+	require.Nil(t, err)
+	_, err = addOrGroup(`[]`)
+	require.Error(t, err)
+}
+func TestAddNotFilter(t *testing.T) {
+	_, _ = initializeObjects("{}", "[]")
+	_, err := addNotFilter("facetGroupName", "facetName", true, 0, true, 10)
+	require.Nil(t, err)
+	_, err = addNotFilter(" ", "facetName", true, 0, true, 10)
+	require.Error(t, err)
+}
+func TestUpdateAndListFilters(t *testing.T) {
+	_, _ = initializeObjects("{}", "[]")
+	name, err := addFilter("group", "facet", true, 0, true, 10)
+	require.Nil(t, err)
+	_, err = addExpression(`{"name":"slider","facetGroupName":"group","facetName":"other","min":1,"max":2}`)
+	require.Nil(t, err)
+	err = updateFilter(name, "group", "facet", true, 5, false, 10)
+	require.Nil(t, err)
+	err = updateExpression("slider", `{"not":{"facetGroupName":"group","facetName":"other","min":1,"max":2}}`)
+	require.Nil(t, err)
+	err = updateExpression("missing", `{"facetGroupName":"group","facetName":"other","min":1,"max":2}`)
+	require.Error(t, err)
+	filters, err := listFilters()
+	require.Nil(t, err)
+	require.Equal(t, `[{"name":"filter-1","facetGroupName":"group","facetName":"facet","inclusiveMin":true,"min":5,"max":10},{"name":"slider","not":{"facetGroupName":"group","facetName":"other","min":1,"max":2}}]`, filters)
+}
+func TestHistogram(t *testing.T) {
+	_, _ = initializeObjects(`{"arrayDotNotation":"measurements","nameFieldDotNotation":"measurementName","nameMetaDotNotation":"metrics.metricName","valueMapDotNotation":"metrics.measurements"}`, readmeExample)
+	buckets, err := histogram("area (cube)", "side", `{"width":10}`)
+	require.Nil(t, err)
+	require.Equal(t, `[{"min":10,"max":20,"count":1},{"min":20,"max":30,"count":1}]`, buckets)
+	_, err = histogram("area (cube)", "side", `{}`)
+	require.Error(t, err)
+	_, err = histogram("area (cube)", "missing", `{"count":2}`)
+	require.Error(t, err)
+}
+func TestStatisticsPercentiles(t *testing.T) {
+	_, _ = initializeObjects(`{"arrayDotNotation":"measurements","nameFieldDotNotation":"measurementName","nameMetaDotNotation":"metrics.metricName","valueMapDotNotation":"metrics.measurements"}`, readmeExample)
+	results, err := statistics("area (cube)", "side", `[50]`)
+	require.Nil(t, err)
+	require.Equal(t, `{"count":2,"min":10,"max":20,"sum":30,"mean":15,"stdDev":5,"percentiles":{"50":15}}`, results)
+	_, err = statistics("area (cube)", "side", `NOTJSON`)
+	require.Error(t, err)
+	_, err = statistics("area (cube)", "missing", `[]`)
+	require.Error(t, err)
+	require.Nil(t, setPercentiles(`[10, 90]`))
+	require.Error(t, setPercentiles(`[101]`))
+	require.Error(t, setPercentiles(`NOTJSON`))
+}
+func TestDeleteRecordsCallback(t *testing.T) {
+	_, _ = initializeObjects(`{"arrayDotNotation":"measurements","nameFieldDotNotation":"measurementName","nameMetaDotNotation":"metrics.metricName","valueMapDotNotation":"metrics.measurements"}`, readmeExample)
+	require.Nil(t, deleteRecords(`["record 1"]`))
+	ids, _, err := query()
+	require.Nil(t, err)
+	require.Equal(t, `["record 2"]`, ids)
+	require.Error(t, deleteRecords(`["record 1"]`))
+	require.Error(t, deleteRecords(`NOTJSON`))
+}
+func TestClearFilter(t *testing.T) {
+	_, _ = initializeObjects("{}", "[]")
+	JSClearFilters(nil)
+}
+func TestInitializeErrorKeepsEngine(t *testing.T) {
+	_, _ = initializeObjects(`{"arrayDotNotation":"measurements","nameFieldDotNotation":"measurementName","nameMetaDotNotation":"metrics.metricName","valueMapDotNotation":"metrics.measurements"}`, readmeExample)
+	_, err := initializeObjects(`{}`, `NOTJSON`)
+	require.Equal(t, facetengine.ErrInvalidJSON, facetengine.ToError(err).Code)
+	_, err = initializeObjects(`NOTJSON`, `[]`)
+	require.Equal(t, facetengine.ErrInvalidJSON, facetengine.ToError(err).Code)
+	ids, _, err := query()
+	require.Nil(t, err)
+	require.Contains(t, ids, `"record 1"`)
+	require.Contains(t, ids, `"record 2"`)
+}
+func TestErrorCodes(t *testing.T) {
+	_, _ = initializeObjects("{}", "[]")
+	_, err := addFilter(" ", "facetName", true, 0, true, 10)
+	require.Equal(t, facetengine.ErrInvalidFilter, facetengine.ToError(err).Code)
+	require.Equal(t, facetengine.ErrUnknownFilter, facetengine.ToError(facetEngine.RemoveFilter("missing")).Code)
+	_, err = histogram("group", "facet", `{"width":10}`)
+	require.Equal(t, facetengine.ErrUnknownFacet, facetengine.ToError(err).Code)
+	require.Equal(t, facetengine.ErrInternal, facetengine.ToError(errors.New("boom")).Code)
+	errorBytes, err := json.Marshal(&facetengine.Error{Code: facetengine.ErrMissingID, Message: "found record with no id", Path: "id"})
+	require.Nil(t, err)
+	require.Equal(t, `{"code":"missing_id","message":"found record with no id","path":"id"}`, string(errorBytes))
+}
+func TestInitializeRecordsAndOrdinals(t *testing.T) {
+	var genericObjects []map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(readmeExample), &genericObjects))
+	facetGroups, err := initializeRecords(readmeFacetPath, genericObjects)
+	require.Nil(t, err)
+	require.Equal(t, 2, facetGroups["area (cube)"].Count)
+
+	_, err = initializeRecords(readmeFacetPath, []map[string]interface{}{{"measurements": []interface{}{}}})
+	require.Equal(t, facetengine.ErrMissingID, facetengine.ToError(err).Code)
+
+	_, err = addFilter("area (cube)", "side", true, 15, true, 25)
+	require.Nil(t, err)
+	ids, ordinals, _, err := queryOrdinals()
+	require.Nil(t, err)
+	require.Equal(t, []string{"record 2"}, ids)
+	require.Equal(t, []uint32{1}, ordinals)
+
+	require.Nil(t, deleteOrdinals([]uint32{1}))
+	require.Error(t, deleteOrdinals([]uint32{1}))
+	ids, _, _, err = queryOrdinals()
+	require.Nil(t, err)
+	require.Empty(t, ids)
+}
+func TestRestoreSnapshot(t *testing.T) {
+	_, _ = initializeObjects(`{"arrayDotNotation":"measurements","nameFieldDotNotation":"measurementName","nameMetaDotNotation":"metrics.metricName","valueMapDotNotation":"metrics.measurements"}`, readmeExample)
+	snapshot := facetEngine.Snapshot()
+	_, _ = initializeObjects("{}", "[]")
+	facetGroups, err := restoreSnapshot(snapshot)
+	require.Nil(t, err)
+	require.Equal(t, 2, facetGroups["area (cube)"].Count)
+
+	_, err = restoreSnapshot(snapshot[:10])
+	require.Equal(t, facetengine.ErrInvalidArgument, facetengine.ToError(err).Code)
+	ids, _, err := query()
+	require.Nil(t, err)
+	require.Contains(t, ids, `"record 1"`)
+}
+func TestIngest(t *testing.T) {
+	_, _ = initializeObjects("{}", "[]")
+	_, err := ingestChunk([]byte("{}"))
+	require.Equal(t, facetengine.ErrNotInitialized, facetengine.ToError(err).Code)
+
+	progress := []int{}
+	require.Nil(t, startIngest(`{"arrayDotNotation":"measurements","nameFieldDotNotation":"measurementName","nameMetaDotNotation":"metrics.metricName","valueMapDotNotation":"metrics.measurements"}`, func(records int) {
+		progress = append(progress, records)
+	}))
+	records, err := ingestChunk([]byte(`{"id": "record 1", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "10"}}}]}` + "\n" + `{"id": "rec`))
+	require.Nil(t, err)
+	require.Equal(t, 1, records)
+	records, err = ingestChunk([]byte(`ord 2", "measurements": []}`))
+	require.Nil(t, err)
+	require.Equal(t, 1, records)
+	ids, _, err := query()
+	require.Nil(t, err)
+	require.Equal(t, "[]", ids)
+
+	facetGroups, err := finishIngest()
+	require.Nil(t, err)
+	require.Equal(t, 1, facetGroups["area (cube)"].Count)
+	require.Equal(t, []int{1, 2}, progress)
+	ids, _, err = query()
+	require.Nil(t, err)
+	require.Contains(t, ids, `"record 2"`)
+
+	require.Nil(t, startIngest(`{}`, nil))
+	_, err = ingestChunk([]byte("NOTJSON\n"))
+	require.Equal(t, facetengine.ErrInvalidJSON, facetengine.ToError(err).Code)
+	_, err = finishIngest()
+	require.Equal(t, facetengine.ErrInvalidJSON, facetengine.ToError(err).Code)
+	ids, _, err = query()
+	require.Nil(t, err)
+	require.Contains(t, ids, `"record 2"`)
+
+	err = startIngest(`{"caseFolding": "upper"}`, nil)
+	require.Equal(t, facetengine.ErrInvalidArgument, facetengine.ToError(err).Code)
+	require.Equal(t, "caseFolding", facetengine.ToError(err).Path)
+}
+func TestMemoryUsage(t *testing.T) {
+	_, _ = initializeObjects(`{"arrayDotNotation":"measurements","nameFieldDotNotation":"measurementName","nameMetaDotNotation":"metrics.metricName","valueMapDotNotation":"metrics.measurements","retainRecords":true}`, readmeExample)
+	usage, err := memoryUsage()
+	require.Nil(t, err)
+	require.Contains(t, usage, `"records":2`)
+	require.Contains(t, usage, `"retainRecords":true`)
+	record, err := facetEngine.Record("record 1")
+	require.Nil(t, err)
+	require.Equal(t, "record 1", record["id"])
+}
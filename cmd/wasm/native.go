@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+
+	facetengine "github.com/codeallthethingz/wasm-facet-engine"
+	"github.com/gopherjs/gopherwasm/js"
+)
+
+// fromJSValue copy a javascript value in to the same go types json.Unmarshal decodes in to, without going through a
+// string.
+func fromJSValue(value js.Value) interface{} {
+	switch value.Type() {
+	case js.TypeString:
+		return value.String()
+	case js.TypeNumber:
+		return value.Float()
+	case js.TypeBoolean:
+		return value.Bool()
+	case js.TypeObject:
+		if js.Global().Get("Array").Call("isArray", value).Bool() {
+			results := make([]interface{}, value.Length())
+			for i := range results {
+				results[i] = fromJSValue(value.Index(i))
+			}
+			return results
+		}
+		keys := js.Global().Get("Object").Call("keys", value)
+		results := make(map[string]interface{}, keys.Length())
+		for i := 0; i < keys.Length(); i++ {
+			key := keys.Index(i).String()
+			results[key] = fromJSValue(value.Get(key))
+		}
+		return results
+	}
+	return nil
+}
+
+// objectsFromJSValue copy a javascript array of objects.
+func objectsFromJSValue(value js.Value) ([]map[string]interface{}, error) {
+	array, ok := fromJSValue(value).([]interface{})
+	if !ok {
+		return nil, facetengine.NewError(facetengine.ErrInvalidArgument, "expected an array of objects")
+	}
+	return toObjects(array)
+}
+
+func toObjects(array []interface{}) ([]map[string]interface{}, error) {
+	results := make([]map[string]interface{}, len(array))
+	for i, item := range array {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, facetengine.NewError(facetengine.ErrInvalidArgument, "expected an object at index %d", i)
+		}
+		results[i] = object
+	}
+	return results, nil
+}
+
+// facetPathFromJSValue read the configuration object passed to initializeRecords.
+func facetPathFromJSValue(value js.Value) (*facetengine.FacetPath, error) {
+	object, ok := fromJSValue(value).(map[string]interface{})
+	if !ok {
+		return nil, facetengine.NewError(facetengine.ErrInvalidArgument, "expected a configuration object")
+	}
+	return toFacetPath(object)
+}
+
+// toFacetPath decode the configuration object the same way initializeObjects decodes the stringified one.
+func toFacetPath(object map[string]interface{}) (*facetengine.FacetPath, error) {
+	configBytes, err := json.Marshal(object)
+	if err != nil {
+		return nil, facetengine.ToError(err)
+	}
+	facetPath := &facetengine.FacetPath{}
+	if err := json.Unmarshal(configBytes, facetPath); err != nil {
+		return nil, facetengine.NewError(facetengine.ErrInvalidArgument, "invalid configuration: %v", err)
+	}
+	return facetPath, nil
+}
+
+// ordinalsFromJSValue copy a typed array, or plain array, of record ordinals.
+func ordinalsFromJSValue(value js.Value) []uint32 {
+	results := make([]uint32, value.Length())
+	for i := range results {
+		results[i] = uint32(value.Index(i).Int())
+	}
+	return results
+}
+
+// ordinalsToJSValue copy the ordinals in to a new Uint32Array that stays valid after the go memory is reused.
+func ordinalsToJSValue(ordinals []uint32) js.Value {
+	typedArray := js.TypedArrayOf(ordinals)
+	defer typedArray.Release()
+	return js.Global().Get("Uint32Array").New(typedArray)
+}
+
+// bytesFromJSValue copy a Uint8Array in to go memory with a single call rather than an Index per byte.
+func bytesFromJSValue(value js.Value) []byte {
+	results := make([]byte, value.Length())
+	typedArray := js.TypedArrayOf(results)
+	defer typedArray.Release()
+	typedArray.Call("set", value)
+	return results
+}
+
+// bytesToJSValue copy the bytes in to a new Uint8Array that stays valid after the go memory is reused.
+func bytesToJSValue(data []byte) js.Value {
+	typedArray := js.TypedArrayOf(data)
+	defer typedArray.Release()
+	return js.Global().Get("Uint8Array").New(typedArray)
+}
+
+// stringsToNative build the value js.ValueOf turns in to an array of strings.
+func stringsToNative(values []string) []interface{} {
+	results := make([]interface{}, len(values))
+	for i, value := range values {
+		results[i] = value
+	}
+	return results
+}
+
+// facetGroupsToNative build the value js.ValueOf turns in to the same object as JSON.parse of the marshalled facet
+// groups.
+func facetGroupsToNative(facetGroups map[string]*facetengine.FacetGroup) map[string]interface{} {
+	results := make(map[string]interface{}, len(facetGroups))
+	for key, facetGroup := range facetGroups {
+		group := map[string]interface{}{"count": facetGroup.Count}
+		if facetGroup.Name != "" {
+			group["name"] = facetGroup.Name
+		}
+		if len(facetGroup.Facets) > 0 {
+			facets := make(map[string]interface{}, len(facetGroup.Facets))
+			for facetKey, facet := range facetGroup.Facets {
+				facets[facetKey] = facetToNative(facet)
+			}
+			group["facets"] = facets
+		}
+		results[key] = group
+	}
+	return results
+}
+
+func facetToNative(facet *facetengine.Facet) map[string]interface{} {
+	result := map[string]interface{}{"count": facet.Count}
+	if facet.Name != "" {
+		result["name"] = facet.Name
+	}
+	if facet.Categorical {
+		result["categorical"] = true
+	}
+	if facet.Values != nil {
+		values := facet.Values.ToArray()
+		sort.Strings(values)
+		result["values"] = stringsToNative(values)
+	}
+	if len(facet.Counts) > 0 {
+		counts := make(map[string]interface{}, len(facet.Counts))
+		for value, count := range facet.Counts {
+			counts[value] = count
+		}
+		result["counts"] = counts
+	}
+	if facet.Statistics != nil {
+		result["statistics"] = statisticsToNative(facet.Statistics)
+	}
+	return result
+}
+
+func statisticsToNative(statistics *facetengine.Statistics) map[string]interface{} {
+	result := map[string]interface{}{
+		"count":  statistics.Count,
+		"min":    statistics.Min,
+		"max":    statistics.Max,
+		"sum":    statistics.Sum,
+		"mean":   statistics.Mean,
+		"stdDev": statistics.StdDev,
+	}
+	if len(statistics.Percentiles) > 0 {
+		percentiles := make(map[string]interface{}, len(statistics.Percentiles))
+		for percentile, value := range statistics.Percentiles {
+			percentiles[percentile] = value
+		}
+		result["percentiles"] = percentiles
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	facetengine "github.com/codeallthethingz/wasm-facet-engine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFacetGroupsToNative(t *testing.T) {
+	facetEngine, facetGroups, err := facetengine.NewFacetEngine(categoricalExample, categoricalFacetPath)
+	require.Nil(t, err)
+	require.Nil(t, facetEngine.SetPercentiles(50))
+	_, facetGroups, err = facetEngine.Query()
+	require.Nil(t, err)
+	expected, err := json.Marshal(facetGroups)
+	require.Nil(t, err)
+	actual, err := json.Marshal(facetGroupsToNative(facetGroups))
+	require.Nil(t, err)
+	require.JSONEq(t, string(expected), string(actual))
+}
+
+func TestToFacetPath(t *testing.T) {
+	var object map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(`{"idDotNotation":"key","arrayDotNotation":"bounds","categoricalFacets":["material"]}`), &object))
+	facetPath, err := toFacetPath(object)
+	require.Nil(t, err)
+	require.Equal(t, &facetengine.FacetPath{IDDotNotation: "key", ArrayDotNotation: "bounds", CategoricalFacets: []string{"material"}}, facetPath)
+
+	require.Nil(t, json.Unmarshal([]byte(`{"rules":[{"arrayDotNotation":"labels","groupName":"{meta}"}],"retainRecords":true}`), &object))
+	facetPath, err = toFacetPath(object)
+	require.Nil(t, err)
+	require.Equal(t, []*facetengine.ExtractionRule{{ArrayDotNotation: "labels", GroupName: "{meta}"}}, facetPath.Rules)
+	require.True(t, facetPath.RetainRecords)
+
+	_, err = toFacetPath(map[string]interface{}{"arrayDotNotation": 1.0})
+	require.Equal(t, facetengine.ErrInvalidArgument, facetengine.ToError(err).Code)
+	_, err = toFacetPath(map[string]interface{}{"categoricalFacets": []interface{}{1.0}})
+	require.Equal(t, facetengine.ErrInvalidArgument, facetengine.ToError(err).Code)
+}
+
+func TestToObjects(t *testing.T) {
+	objects, err := toObjects([]interface{}{map[string]interface{}{"id": "1"}})
+	require.Nil(t, err)
+	require.Equal(t, []map[string]interface{}{{"id": "1"}}, objects)
+	_, err = toObjects([]interface{}{"1"})
+	require.Equal(t, facetengine.ErrInvalidArgument, facetengine.ToError(err).Code)
+}
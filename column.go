@@ -0,0 +1,172 @@
+package facetengine
+
+import (
+	"math"
+	"sort"
+)
+
+// Column is the index of one facet, each entry is a value of the facet in a record paired with the record's ordinal.
+// Numeric columns are kept sorted by number, so a range filter is a binary search rather than a scan.  Entries are
+// appended unsorted and the engine sorts them in before it returns from adding or removing records, so queries only
+// read the column.  Group and Facet are the keys it is looked up by, the labels are the names to show.
+type Column struct {
+	Group       string
+	Facet       string
+	GroupLabel  string
+	FacetLabel  string
+	Categorical bool
+	Values      []string
+	Numbers     []float64
+	Ordinals    []uint32
+	// sorted is the number of entries at the start that are in order, the rest were added since the last sort.
+	sorted int
+	// numeric is the number of entries that are not NaN, which sort after every number.
+	numeric int
+}
+
+// Len return the number of entries.
+func (c *Column) Len() int {
+	return len(c.Ordinals)
+}
+
+// add an entry.  number is ignored for categorical columns.
+func (c *Column) add(value string, number float64, ordinal uint32) {
+	c.Values = append(c.Values, value)
+	c.Ordinals = append(c.Ordinals, ordinal)
+	if !c.Categorical {
+		c.Numbers = append(c.Numbers, number)
+	}
+}
+
+// remove the entries of the ordinals, keeping the rest in order.  The column must be sorted again afterwards.
+func (c *Column) remove(ordinals *Bitmap) {
+	kept := 0
+	sorted := 0
+	for i, ordinal := range c.Ordinals {
+		if ordinals.Contains(ordinal) {
+			continue
+		}
+		c.Values[kept] = c.Values[i]
+		c.Ordinals[kept] = ordinal
+		if !c.Categorical {
+			c.Numbers[kept] = c.Numbers[i]
+		}
+		if i < c.sorted {
+			sorted++
+		}
+		kept++
+	}
+	c.Values = c.Values[:kept]
+	c.Ordinals = c.Ordinals[:kept]
+	if !c.Categorical {
+		c.Numbers = c.Numbers[:kept]
+	}
+	c.sorted = sorted
+}
+
+// sort the entries added since the last time in to the ones already in order.
+func (c *Column) sort() {
+	if c.Categorical {
+		return
+	}
+	if c.sorted < len(c.Numbers) {
+		sort.Sort(byNumber{c: c, start: c.sorted})
+		c.merge()
+	}
+	c.sorted = len(c.Numbers)
+	c.numeric = sort.Search(len(c.Numbers), func(i int) bool { return math.IsNaN(c.Numbers[i]) })
+}
+
+// merge the entries already in order with the sorted entries added after them.
+func (c *Column) merge() {
+	if c.sorted == 0 {
+		return
+	}
+	n := len(c.Numbers)
+	numbers := make([]float64, 0, n)
+	values := make([]string, 0, n)
+	ordinals := make([]uint32, 0, n)
+	i, j := 0, c.sorted
+	for i < c.sorted || j < n {
+		k := i
+		if i == c.sorted || (j < n && numberLess(c.Numbers[j], c.Numbers[i])) {
+			k = j
+			j++
+		} else {
+			i++
+		}
+		numbers = append(numbers, c.Numbers[k])
+		values = append(values, c.Values[k])
+		ordinals = append(ordinals, c.Ordinals[k])
+	}
+	c.Numbers = numbers
+	c.Values = values
+	c.Ordinals = ordinals
+}
+
+// Between return the ordinals of the records with a number between min and max.  The column must be sorted, which the
+// engine does whenever records are added or removed.
+func (c *Column) Between(min Range, max Range) *Bitmap {
+	results := NewBitmap()
+	if c.Categorical {
+		return results
+	}
+	numbers := c.Numbers[:c.numeric]
+	start := sort.Search(len(numbers), func(i int) bool {
+		return numbers[i] > min.Value() || (min.IsInclusive() && numbers[i] == min.Value())
+	})
+	end := sort.Search(len(numbers), func(i int) bool {
+		return numbers[i] > max.Value() || (!max.IsInclusive() && numbers[i] == max.Value())
+	})
+	for i := start; i < end; i++ {
+		results.Add(c.Ordinals[i])
+	}
+	return results
+}
+
+// Matching return the ordinals of the records with a value the filter matches.
+func (c *Column) Matching(filter filter) *Bitmap {
+	if filter.Match == MatchRange && !c.Categorical {
+		return c.Between(filter.Min, filter.Max)
+	}
+	results := NewBitmap()
+	for i, value := range c.Values {
+		if filter.matches(value) {
+			results.Add(c.Ordinals[i])
+		}
+	}
+	return results
+}
+
+// entry is a value a record has in a column, a record counts once for each distinct value it has.
+type entry struct {
+	ordinal uint32
+	value   string
+}
+
+// numberLess orders numbers with NaN last.
+func numberLess(x, y float64) bool {
+	return x < y || (!math.IsNaN(x) && math.IsNaN(y))
+}
+
+// byNumber sorts the entries of a column from start onwards by number.
+type byNumber struct {
+	c     *Column
+	start int
+}
+
+func (b byNumber) Len() int {
+	return len(b.c.Numbers) - b.start
+}
+
+func (b byNumber) Less(i, j int) bool {
+	return numberLess(b.c.Numbers[b.start+i], b.c.Numbers[b.start+j])
+}
+
+func (b byNumber) Swap(i, j int) {
+	c := b.c
+	i, j = b.start+i, b.start+j
+	c.Numbers[i], c.Numbers[j] = c.Numbers[j], c.Numbers[i]
+	c.Values[i], c.Values[j] = c.Values[j], c.Values[i]
+	c.Ordinals[i], c.Ordinals[j] = c.Ordinals[j], c.Ordinals[i]
+}
@@ -0,0 +1,58 @@
+package facetengine
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func numericColumn(numbers ...float64) *Column {
+	column := &Column{}
+	for i, number := range numbers {
+		column.add("", number, uint32(i))
+	}
+	column.sort()
+	return column
+}
+
+func TestColumnBetween(t *testing.T) {
+	column := numericColumn(20, 10, math.NaN(), 15, 10, 30)
+	require.Equal(t, []uint32{1, 3, 4}, column.Between(Inclusive(10), Exclusive(20)).ToArray())
+	require.Equal(t, []uint32{0, 3}, column.Between(Exclusive(10), Inclusive(20)).ToArray())
+	require.Equal(t, []uint32{0, 1, 3, 4, 5}, column.Between(Inclusive(math.Inf(-1)), Inclusive(math.Inf(1))).ToArray())
+	require.Equal(t, []uint32{}, column.Between(Exclusive(10), Exclusive(15)).ToArray())
+	require.Equal(t, []uint32{}, column.Between(Inclusive(20), Inclusive(10)).ToArray())
+	require.Equal(t, []float64{10, 10, 15, 20, 30}, column.Numbers[:column.numeric])
+}
+
+func TestColumnSortsAfterAdd(t *testing.T) {
+	column := numericColumn(5, 1)
+	require.Equal(t, []uint32{1}, column.Between(Inclusive(0), Inclusive(2)).ToArray())
+	column.add("0", 0, 7)
+	column.add("nan", math.NaN(), 8)
+	column.add("3", 3, 9)
+	column.sort()
+	require.Equal(t, []float64{0, 1, 3, 5}, column.Numbers[:column.numeric])
+	require.Equal(t, []uint32{7, 1, 9, 0, 8}, column.Ordinals)
+	require.Equal(t, []uint32{1, 7}, column.Between(Inclusive(0), Inclusive(2)).ToArray())
+}
+
+func TestColumnRemove(t *testing.T) {
+	column := numericColumn(3, 2, 1)
+	column.remove(BitmapOf(1))
+	column.sort()
+	require.Equal(t, 2, column.Len())
+	require.Equal(t, []uint32{0, 2}, column.Between(Inclusive(0), Inclusive(5)).ToArray())
+}
+
+func TestColumnMatching(t *testing.T) {
+	column := &Column{Categorical: true}
+	column.add("steel", 0, 0)
+	column.add("brass", 0, 1)
+	column.add("2", 0, 2)
+	require.Equal(t, []uint32{0}, column.Matching(*Prefix("g", "f", "st").Filter).ToArray())
+	require.Equal(t, []uint32{0, 2}, column.Matching(*NotIn("g", "f", "brass").Filter).ToArray())
+	require.Equal(t, []uint32{}, column.Between(Inclusive(0), Inclusive(5)).ToArray())
+	require.Equal(t, []uint32{2}, column.Matching(*NewFilter("g", "f", Inclusive(0), Inclusive(5)).Filter).ToArray())
+}
@@ -0,0 +1,54 @@
+package facetengine
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Error codes say what kind of failure an Error is.
+const (
+	ErrInvalidJSON     = "invalid_json"
+	ErrInvalidArgument = "invalid_argument"
+	ErrInvalidFilter   = "invalid_filter"
+	ErrInvalidNumber   = "invalid_number"
+	ErrMissingID       = "missing_id"
+	ErrDuplicateRecord = "duplicate_record"
+	ErrUnknownRecord   = "unknown_record"
+	ErrUnknownFilter   = "unknown_filter"
+	ErrUnknownFacet    = "unknown_facet"
+	ErrNotNumeric      = "not_numeric"
+	ErrNotInitialized  = "not_initialized"
+	ErrInternal        = "internal"
+)
+
+// Error is a failure with enough structure to be reported back to javascript.  RecordID and Path say where in the
+// input the failure is when it is known.
+type Error struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	RecordID string `json:"recordId,omitempty"`
+	Path     string `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError create an Error with the code and a formatted message.
+func NewError(code string, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// ToError give any error a code so it can be reported, errors that are already an *Error are returned unchanged.
+func ToError(err error) *Error {
+	switch e := err.(type) {
+	case *Error:
+		return e
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return &Error{Code: ErrInvalidJSON, Message: err.Error()}
+	}
+	return &Error{Code: ErrInternal, Message: err.Error()}
+}
@@ -0,0 +1,293 @@
+package facetengine
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Operator says how an Expression combines its children.
+type Operator int
+
+const (
+	// OpFilter is a leaf that matches records with a single filter.
+	OpFilter Operator = iota
+	// OpAnd matches records that match every child.
+	OpAnd
+	// OpOr matches records that match any child.
+	OpOr
+	// OpNot matches records that do not match its only child.
+	OpNot
+)
+
+// Expression is a node in a tree of filters.  Name identifies filters added directly to the query.
+type Expression struct {
+	Name     string
+	Operator Operator
+	Filter   *filter
+	Children []*Expression
+}
+
+// NewFilter creates a leaf expression matching facet values between min and max.
+func NewFilter(facetGroupName string, facetName string, min Range, max Range) *Expression {
+	return &Expression{
+		Operator: OpFilter,
+		Filter: &filter{
+			FacetGroupName: facetGroupName,
+			FacetName:      facetName,
+			Min:            min,
+			Max:            max,
+		},
+	}
+}
+
+// Equals creates a leaf expression matching facet values that are exactly value.
+func Equals(facetGroupName string, facetName string, value string) *Expression {
+	return newValueFilter(facetGroupName, facetName, MatchEquals, []string{value})
+}
+
+// In creates a leaf expression matching facet values that are any of the values.
+func In(facetGroupName string, facetName string, values ...string) *Expression {
+	return newValueFilter(facetGroupName, facetName, MatchIn, values)
+}
+
+// NotIn creates a leaf expression matching facet values that are none of the values.  Records without the facet do
+// not match.
+func NotIn(facetGroupName string, facetName string, values ...string) *Expression {
+	return newValueFilter(facetGroupName, facetName, MatchNotIn, values)
+}
+
+// Prefix creates a leaf expression matching facet values that start with prefix.
+func Prefix(facetGroupName string, facetName string, prefix string) *Expression {
+	return newValueFilter(facetGroupName, facetName, MatchPrefix, []string{prefix})
+}
+
+// NewValueFilter creates a leaf expression from the name of the match, one of equals, in, notIn or prefix.
+func NewValueFilter(facetGroupName string, facetName string, match string, values ...string) (*Expression, error) {
+	for m, name := range matchNames {
+		if name == match {
+			return newValueFilter(facetGroupName, facetName, m, values), nil
+		}
+	}
+	return nil, NewError(ErrInvalidFilter, "unknown match %s", match)
+}
+
+func newValueFilter(facetGroupName string, facetName string, match Match, values []string) *Expression {
+	return &Expression{
+		Operator: OpFilter,
+		Filter: &filter{
+			FacetGroupName: facetGroupName,
+			FacetName:      facetName,
+			Match:          match,
+			Values:         values,
+		},
+	}
+}
+
+// And creates an expression matching records that match all of the children.
+func And(children ...*Expression) *Expression {
+	return &Expression{Operator: OpAnd, Children: children}
+}
+
+// Or creates an expression matching records that match any of the children.
+func Or(children ...*Expression) *Expression {
+	return &Expression{Operator: OpOr, Children: children}
+}
+
+// Not creates an expression matching records that do not match the child.
+func Not(child *Expression) *Expression {
+	return &Expression{Operator: OpNot, Children: []*Expression{child}}
+}
+
+// Validate check the expression tree is well formed.
+func (e *Expression) Validate() error {
+	if e == nil {
+		return NewError(ErrInvalidFilter, "must specify an expression")
+	}
+	switch e.Operator {
+	case OpFilter:
+		if e.Filter == nil {
+			return NewError(ErrInvalidFilter, "must specify a filter")
+		}
+		if strings.TrimSpace(e.Filter.FacetGroupName) == "" {
+			return NewError(ErrInvalidFilter, "must specify facetgroup name")
+		}
+		if strings.TrimSpace(e.Filter.FacetName) == "" {
+			return NewError(ErrInvalidFilter, "must specify facet name")
+		}
+		switch e.Filter.Match {
+		case MatchRange:
+			if e.Filter.Min == nil || e.Filter.Max == nil {
+				return NewError(ErrInvalidFilter, "must specify min and max")
+			}
+		case MatchEquals, MatchPrefix:
+			if len(e.Filter.Values) != 1 {
+				return NewError(ErrInvalidFilter, "%s takes exactly one value", matchNames[e.Filter.Match])
+			}
+		case MatchIn, MatchNotIn:
+			if len(e.Filter.Values) == 0 {
+				return NewError(ErrInvalidFilter, "%s must specify at least one value", matchNames[e.Filter.Match])
+			}
+		default:
+			return NewError(ErrInvalidFilter, "unknown match %d", e.Filter.Match)
+		}
+	case OpAnd, OpOr:
+		if len(e.Children) == 0 {
+			return NewError(ErrInvalidFilter, "must specify at least one child expression")
+		}
+	case OpNot:
+		if len(e.Children) != 1 {
+			return NewError(ErrInvalidFilter, "not takes exactly one child expression")
+		}
+	default:
+		return NewError(ErrInvalidFilter, "unknown operator %d", e.Operator)
+	}
+	for _, child := range e.Children {
+		if err := child.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// facetOf return the facet that every filter in the expression is on.  ok is false when the filters are on more than
+// one facet.
+func (e *Expression) facetOf() (key FacetKey, ok bool) {
+	if e.Operator == OpFilter {
+		return FacetKey{Group: e.Filter.FacetGroupName, Facet: e.Filter.FacetName}, true
+	}
+	for i, child := range e.Children {
+		childKey, childOk := child.facetOf()
+		if !childOk || (i > 0 && childKey != key) {
+			return FacetKey{}, false
+		}
+		key = childKey
+	}
+	return key, len(e.Children) > 0
+}
+
+// expressionJSON is the wire format of an Expression.  Exactly one of And, Or, Not or FacetGroupName is expected.  Leaves
+// are ranges unless one of Equals, In, NotIn or Prefix is set.
+type expressionJSON struct {
+	Name           string            `json:"name,omitempty"`
+	And            []*expressionJSON `json:"and,omitempty"`
+	Or             []*expressionJSON `json:"or,omitempty"`
+	Not            *expressionJSON   `json:"not,omitempty"`
+	FacetGroupName string            `json:"facetGroupName,omitempty"`
+	FacetName      string            `json:"facetName,omitempty"`
+	InclusiveMin   bool              `json:"inclusiveMin,omitempty"`
+	Min            float64           `json:"min,omitempty"`
+	InclusiveMax   bool              `json:"inclusiveMax,omitempty"`
+	Max            float64           `json:"max,omitempty"`
+	Equals         *string           `json:"equals,omitempty"`
+	In             []string          `json:"in,omitempty"`
+	NotIn          []string          `json:"notIn,omitempty"`
+	Prefix         *string           `json:"prefix,omitempty"`
+}
+
+// MarshalJSON writes the expression as nested and/or/not objects with range leaves.
+func (e *Expression) MarshalJSON() ([]byte, error) {
+	raw := e.toJSON()
+	raw.Name = e.Name
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON reads the format written by MarshalJSON.
+func (e *Expression) UnmarshalJSON(j []byte) error {
+	var raw expressionJSON
+	err := json.Unmarshal(j, &raw)
+	if err != nil {
+		return ToError(err)
+	}
+	parsed, err := raw.toExpression()
+	if err != nil {
+		return err
+	}
+	*e = *parsed
+	e.Name = raw.Name
+	return nil
+}
+
+func (e *Expression) toJSON() *expressionJSON {
+	switch e.Operator {
+	case OpAnd:
+		return &expressionJSON{And: childrenToJSON(e.Children)}
+	case OpOr:
+		return &expressionJSON{Or: childrenToJSON(e.Children)}
+	case OpNot:
+		return &expressionJSON{Not: e.Children[0].toJSON()}
+	}
+	switch e.Filter.Match {
+	case MatchEquals:
+		return &expressionJSON{FacetGroupName: e.Filter.FacetGroupName, FacetName: e.Filter.FacetName, Equals: &e.Filter.Values[0]}
+	case MatchIn:
+		return &expressionJSON{FacetGroupName: e.Filter.FacetGroupName, FacetName: e.Filter.FacetName, In: e.Filter.Values}
+	case MatchNotIn:
+		return &expressionJSON{FacetGroupName: e.Filter.FacetGroupName, FacetName: e.Filter.FacetName, NotIn: e.Filter.Values}
+	case MatchPrefix:
+		return &expressionJSON{FacetGroupName: e.Filter.FacetGroupName, FacetName: e.Filter.FacetName, Prefix: &e.Filter.Values[0]}
+	}
+	return &expressionJSON{
+		FacetGroupName: e.Filter.FacetGroupName,
+		FacetName:      e.Filter.FacetName,
+		InclusiveMin:   e.Filter.Min.IsInclusive(),
+		Min:            e.Filter.Min.Value(),
+		InclusiveMax:   e.Filter.Max.IsInclusive(),
+		Max:            e.Filter.Max.Value(),
+	}
+}
+
+func childrenToJSON(children []*Expression) []*expressionJSON {
+	results := make([]*expressionJSON, len(children))
+	for i, child := range children {
+		results[i] = child.toJSON()
+	}
+	return results
+}
+
+func (j *expressionJSON) toExpression() (*Expression, error) {
+	switch {
+	case j.And != nil:
+		children, err := childrenToExpressions(j.And)
+		return And(children...), err
+	case j.Or != nil:
+		children, err := childrenToExpressions(j.Or)
+		return Or(children...), err
+	case j.Not != nil:
+		child, err := j.Not.toExpression()
+		return Not(child), err
+	case j.Equals != nil:
+		return Equals(j.FacetGroupName, j.FacetName, *j.Equals), nil
+	case j.In != nil:
+		return In(j.FacetGroupName, j.FacetName, j.In...), nil
+	case j.NotIn != nil:
+		return NotIn(j.FacetGroupName, j.FacetName, j.NotIn...), nil
+	case j.Prefix != nil:
+		return Prefix(j.FacetGroupName, j.FacetName, *j.Prefix), nil
+	case j.FacetGroupName != "" || j.FacetName != "":
+		min := Exclusive(j.Min)
+		max := Exclusive(j.Max)
+		if j.InclusiveMin {
+			min = Inclusive(j.Min)
+		}
+		if j.InclusiveMax {
+			max = Inclusive(j.Max)
+		}
+		return NewFilter(j.FacetGroupName, j.FacetName, min, max), nil
+	}
+	return nil, NewError(ErrInvalidFilter, "expression must have one of and, or, not or facetGroupName")
+}
+
+func childrenToExpressions(children []*expressionJSON) ([]*Expression, error) {
+	results := make([]*Expression, len(children))
+	for i, child := range children {
+		if child == nil {
+			return nil, NewError(ErrInvalidFilter, "expression must not be null")
+		}
+		expression, err := child.toExpression()
+		if err != nil {
+			return nil, err
+		}
+		results[i] = expression
+	}
+	return results, nil
+}
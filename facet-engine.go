@@ -1,4 +1,6 @@
-package main
+// Package facetengine turns json records in to facets and filters them.  It is shared by the web assembly build in
+// cmd/wasm and any go program that wants the same faceting.
+package facetengine
 
 import (
 	"encoding/json"
@@ -9,97 +11,452 @@ import (
 
 // FacetEngine is a map of FacetGroup
 type FacetEngine struct {
-	RecordLookup   RecordLookup
-	facetPath      *FacetPath
-	ids            *Set
-	allIds         *Set
-	query          *Query
-	initialized    bool
-	genericObjects []map[string]interface{}
+	RecordLookup RecordLookup
+	facetPath    *FacetPath
+	ids          *Set
+	allIds       *Set
+	query        *Query
+	initialized  bool
+	disjunctive  bool
+	percentiles  []float64
+	dictionary   *dictionary
+	// records holds the objects the index was built from, by ordinal, when FacetPath.RetainRecords is set.
+	records map[uint32]map[string]interface{}
+	// paths caches the facet path's paths compiled, keyed by the path as written.
+	paths map[string]selector
+	// groupLabels is the label of each group key, the first one found, so every column of a group is shown the same way.
+	groupLabels map[string]string
 }
 
-// RecordLookup Set of records
-type RecordLookup map[string][]*Record
+// RecordLookup the Column of each facet keyed by its group and facet.
+type RecordLookup map[FacetKey]*Column
 
-// Add a record to the map.
-func (r RecordLookup) Add(key string, record *Record) {
+// FacetKey identifies a facet by the keys of its group and itself, which are the names after case folding.
+type FacetKey struct {
+	Group string `json:"group"`
+	Facet string `json:"facet"`
+}
+
+// Add a value of a record to the facet's column, a new column takes its labels from the value.
+func (r RecordLookup) Add(value *extractedValue, ordinal uint32) {
+	key := FacetKey{Group: value.group, Facet: value.facet}
 	if _, ok := r[key]; !ok {
-		r[key] = []*Record{}
+		r[key] = &Column{
+			Group:       value.group,
+			Facet:       value.facet,
+			GroupLabel:  value.groupLabel,
+			FacetLabel:  value.facetLabel,
+			Categorical: value.categorical,
+		}
 	}
-	r[key] = append(r[key], record)
+	r[key].add(value.value, value.number, ordinal)
 }
 
-// Record holds values and ids for filtering records.
-type Record struct {
-	Value string
-	ID    string
+// sort the entries added to any column since it was last sorted.
+func (r RecordLookup) sort() {
+	for _, column := range r {
+		column.sort()
+	}
 }
 
 // NewFacetEngine create a new one.
 func NewFacetEngine(dataJSON string, config *FacetPath) (*FacetEngine, map[string]*FacetGroup, error) {
-	facetEngine := &FacetEngine{
-		RecordLookup: map[string][]*Record{},
-		ids:          NewSet(),
-		allIds:       NewSet(),
-		query:        &Query{},
-	}
+	facetEngine := newFacetEngine()
 	facetGroups, err := facetEngine.Initialize(dataJSON, config)
 	return facetEngine, facetGroups, err
 }
 
-// FacetGroup contains the description of a facet.
+// newFacetEngine create one that is ready to be initialized.
+func newFacetEngine() *FacetEngine {
+	dictionary := newDictionary()
+	return &FacetEngine{
+		RecordLookup: RecordLookup{},
+		ids:          newSetOf(dictionary, NewBitmap()),
+		allIds:       newSetOf(dictionary, NewBitmap()),
+		query:        newQuery(),
+		dictionary:   dictionary,
+		records:      map[uint32]map[string]interface{}{},
+		paths:        map[string]selector{},
+		groupLabels:  map[string]string{},
+	}
+}
+
+// FacetGroup contains the description of a facet.  Name is the group's label, as it was found before case folding.
+// Count is the number of records that have the group.
 type FacetGroup struct {
 	Name   string            `json:"name,omitempty"`
+	Count  int               `json:"count"`
 	Facets map[string]*Facet `json:"facets,omitempty"`
 }
 
-// Facet contains the values of a facet
+// Facet contains the values of a facet.  Name is the facet's label.  Count is the number of records that have the facet
+// and Counts the number of records that have each value.  Categorical facets hold strings and have no Statistics.
 type Facet struct {
-	Name   string `json:"name,omitempty"`
-	Values *Set   `json:"values,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	Categorical bool           `json:"categorical,omitempty"`
+	Count       int            `json:"count"`
+	Values      *Set           `json:"values,omitempty"`
+	Counts      map[string]int `json:"counts,omitempty"`
+	Statistics  *Statistics    `json:"statistics,omitempty"`
 }
 
-// FacetPath How to get out data from
+// FacetPath How to get out data from.  The array, name, meta and value map paths are the first extraction rule and
+// Rules adds more, so records that keep facets in more than one place can have all of them extracted.
+// CategoricalFacets names the facets that hold strings rather than numbers in every group they appear in, and
+// CategoricalFacetKeys names them by group and facet so a facet name can be categorical in one group only.  Either can
+// use labels or keys.  RetainRecords keeps each object after it is indexed so it can be fetched with Record, otherwise
+// only the index is kept.  Fields are facets read straight from a field of the record.  GroupName is the template for
+// the names of the first rule's groups and of any rule without its own.  CaseFolding is how names are made in to the
+// keys facets are looked up by, CaseFoldingLower by default.
 type FacetPath struct {
-	IDDotNotation        string `json:"idDotNotation,omitempty"`
+	IDDotNotation        string            `json:"idDotNotation,omitempty"`
+	ArrayDotNotation     string            `json:"arrayDotNotation,omitempty"`
+	NameMetaDotNotation  string            `json:"nameMetaDotNotation,omitempty"`
+	NameFieldDotNotation string            `json:"nameFieldDotNotation,omitempty"`
+	ValueMapDotNotation  string            `json:"valueMapDotNotation,omitempty"`
+	Rules                []*ExtractionRule `json:"rules,omitempty"`
+	Fields               []*FieldFacet     `json:"fields,omitempty"`
+	CategoricalFacets    []string          `json:"categoricalFacets,omitempty"`
+	CategoricalFacetKeys []FacetKey        `json:"categoricalFacetKeys,omitempty"`
+	RetainRecords        bool              `json:"retainRecords,omitempty"`
+	GroupName            string            `json:"groupName,omitempty"`
+	CaseFolding          string            `json:"caseFolding,omitempty"`
+}
+
+// Case foldings a FacetPath can use.  Names that fold the same are the same facet, and a filter, histogram or
+// statistics request can name a facet by its label or its key.
+const (
+	// CaseFoldingLower keys facets by their lowercased names.
+	CaseFoldingLower = "lower"
+	// CaseFoldingNone keys facets by their names exactly as found.
+	CaseFoldingNone = "none"
+)
+
+// ExtractionRule is where to find one set of facets.  Each object in the array is a facet group named from its name
+// and meta fields, with a facet for every entry of its value map.  GroupName is a template for the group's name where
+// {name} and {meta} are replaced by the fields, it defaults to "{name} ({meta})".  Fields the template doesn't use
+// aren't required.
+type ExtractionRule struct {
 	ArrayDotNotation     string `json:"arrayDotNotation,omitempty"`
 	NameMetaDotNotation  string `json:"nameMetaDotNotation,omitempty"`
 	NameFieldDotNotation string `json:"nameFieldDotNotation,omitempty"`
 	ValueMapDotNotation  string `json:"valueMapDotNotation,omitempty"`
+	GroupName            string `json:"groupName,omitempty"`
+}
+
+// FieldFacet is a facet whose values are a field of the record, e.g. price, rather than entries of a value map.  The
+// field can be a value or an array of values.  Facet defaults to the last key in the path.
+type FieldFacet struct {
+	DotNotation string `json:"dotNotation"`
+	Group       string `json:"group"`
+	Facet       string `json:"facet,omitempty"`
+}
+
+// name return the labels of the group and facet the field is indexed under.  The facet defaults to the last key in the
+// path, skipping indexes and wildcards.
+func (field *FieldFacet) name() (string, string) {
+	facet := field.Facet
+	if facet == "" && isJSONPath(field.DotNotation) {
+		facet = field.DotNotation
+		if path, err := parseJSONPath(field.DotNotation); err == nil {
+			for _, step := range path.steps {
+				if key, ok := step.(childStep); ok {
+					facet = string(key)
+				}
+			}
+		}
+	}
+	if facet == "" {
+		facet = field.DotNotation
+		for _, segment := range parsePath(field.DotNotation) {
+			if _, err := strconv.Atoi(segment); err != nil && segment != wildcard {
+				facet = segment
+			}
+		}
+	}
+	return field.Group, facet
+}
+
+// validate check the parts of the facet path that can't be checked against a record.
+func (p *FacetPath) validate() error {
+	switch p.CaseFolding {
+	case "", CaseFoldingLower, CaseFoldingNone:
+	default:
+		return &Error{
+			Code:    ErrInvalidArgument,
+			Message: fmt.Sprintf("caseFolding must be %s or %s, not %s", CaseFoldingLower, CaseFoldingNone, p.CaseFolding),
+			Path:    "caseFolding",
+		}
+	}
+	for i, field := range p.Fields {
+		if field == nil || strings.TrimSpace(field.DotNotation) == "" {
+			return &Error{Code: ErrInvalidArgument, Message: "field facets must have a dotNotation", Path: fmt.Sprintf("fields.%d", i)}
+		}
+		if strings.TrimSpace(field.Group) == "" {
+			return &Error{Code: ErrInvalidArgument, Message: fmt.Sprintf("field facet %s must have a group", field.DotNotation), Path: fmt.Sprintf("fields.%d", i)}
+		}
+	}
+	for i, categorical := range p.CategoricalFacetKeys {
+		if strings.TrimSpace(categorical.Group) == "" || strings.TrimSpace(categorical.Facet) == "" {
+			return &Error{Code: ErrInvalidArgument, Message: "categorical facet keys must have a group and a facet", Path: fmt.Sprintf("categoricalFacetKeys.%d", i)}
+		}
+	}
+	paths := [][2]string{
+		{"idDotNotation", p.IDDotNotation},
+		{"arrayDotNotation", p.ArrayDotNotation},
+		{"nameMetaDotNotation", p.NameMetaDotNotation},
+		{"nameFieldDotNotation", p.NameFieldDotNotation},
+		{"valueMapDotNotation", p.ValueMapDotNotation},
+	}
+	for i, rule := range p.Rules {
+		if rule == nil {
+			continue
+		}
+		paths = append(paths,
+			[2]string{fmt.Sprintf("rules.%d.arrayDotNotation", i), rule.ArrayDotNotation},
+			[2]string{fmt.Sprintf("rules.%d.nameMetaDotNotation", i), rule.NameMetaDotNotation},
+			[2]string{fmt.Sprintf("rules.%d.nameFieldDotNotation", i), rule.NameFieldDotNotation},
+			[2]string{fmt.Sprintf("rules.%d.valueMapDotNotation", i), rule.ValueMapDotNotation})
+	}
+	for i, field := range p.Fields {
+		paths = append(paths, [2]string{fmt.Sprintf("fields.%d.dotNotation", i), field.DotNotation})
+	}
+	for _, path := range paths {
+		if _, err := compilePath(path[1]); err != nil {
+			e := ToError(err)
+			e.Path = path[0]
+			return e
+		}
+	}
+	return nil
+}
+
+// defaultGroupName is the GroupName of rules when neither they nor the FacetPath set one.
+const defaultGroupName = "{name} ({meta})"
+
+// rules return the extraction rules, starting with the one made of the FacetPath's own paths when it has them.  Rules
+// without a GroupName take the FacetPath's.
+func (p *FacetPath) rules() []*ExtractionRule {
+	rules := make([]*ExtractionRule, 0, len(p.Rules)+1)
+	if p.ArrayDotNotation != "" || len(p.Rules) == 0 {
+		rules = append(rules, &ExtractionRule{
+			ArrayDotNotation:     p.ArrayDotNotation,
+			NameMetaDotNotation:  p.NameMetaDotNotation,
+			NameFieldDotNotation: p.NameFieldDotNotation,
+			ValueMapDotNotation:  p.ValueMapDotNotation,
+			GroupName:            p.GroupName,
+		})
+	}
+	for _, rule := range p.Rules {
+		if rule == nil {
+			continue
+		}
+		if rule.GroupName == "" && p.GroupName != "" {
+			inherited := *rule
+			inherited.GroupName = p.GroupName
+			rule = &inherited
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// fold turn a name in to the key it is looked up by.
+func (p *FacetPath) fold(name string) string {
+	if p != nil && p.CaseFolding == CaseFoldingNone {
+		return name
+	}
+	return strings.ToLower(name)
+}
+
+// key return the key of the facet with the group and facet names, which may be labels or keys.
+func (p *FacetPath) key(facetGroupName string, facetName string) FacetKey {
+	return FacetKey{Group: p.fold(facetGroupName), Facet: p.fold(facetName)}
 }
 
-// Query represents a set of filters to be applied to the data.
+// groupName fill in the rule's template, ok is false when a field the template uses is blank.  The name is a label,
+// it is not case folded.
+func (r *ExtractionRule) groupName(name string, nameMeta string) (groupName string, ok bool) {
+	template := r.GroupName
+	if template == "" {
+		template = defaultGroupName
+	}
+	if (strings.Contains(template, "{name}") && strings.TrimSpace(name) == "") ||
+		(strings.Contains(template, "{meta}") && strings.TrimSpace(nameMeta) == "") {
+		return "", false
+	}
+	groupName = strings.Replace(template, "{name}", name, -1)
+	groupName = strings.Replace(groupName, "{meta}", nameMeta, -1)
+	return groupName, strings.TrimSpace(groupName) != ""
+}
+
+// isCategorical does the facet with the keys hold strings rather than numbers.
+func (p *FacetPath) isCategorical(groupKey string, facetKey string) bool {
+	if p == nil {
+		return false
+	}
+	for _, categorical := range p.CategoricalFacets {
+		if p.fold(categorical) == facetKey {
+			return true
+		}
+	}
+	for _, categorical := range p.CategoricalFacetKeys {
+		if p.key(categorical.Group, categorical.Facet) == (FacetKey{Group: groupKey, Facet: facetKey}) {
+			return true
+		}
+	}
+	return false
+}
+
+// Query represents a tree of filters to be applied to the data.  The root is an and of everything added.
 type Query struct {
-	Filters []filter
+	Root     *Expression
+	filterID int
+}
+
+func newQuery() *Query {
+	return &Query{Root: And()}
 }
 
 type filter struct {
 	FacetGroupName string
 	FacetName      string
+	Match          Match
 	Min            Range
 	Max            Range
+	Values         []string
+}
+
+// Match is how a filter compares a record's value.
+type Match int
+
+const (
+	// MatchRange matches numeric values between Min and Max.
+	MatchRange Match = iota
+	// MatchEquals matches the single value.
+	MatchEquals
+	// MatchIn matches any of the values.
+	MatchIn
+	// MatchNotIn matches values that are none of the values.
+	MatchNotIn
+	// MatchPrefix matches values that start with the single value.
+	MatchPrefix
+)
+
+var matchNames = map[Match]string{
+	MatchEquals: "equals",
+	MatchIn:     "in",
+	MatchNotIn:  "notIn",
+	MatchPrefix: "prefix",
+}
+
+// matches the value of a record against the filter.
+func (f filter) matches(recordValue string) bool {
+	switch f.Match {
+	case MatchEquals, MatchIn:
+		return containsString(f.Values, recordValue)
+	case MatchNotIn:
+		return !containsString(f.Values, recordValue)
+	case MatchPrefix:
+		return strings.HasPrefix(recordValue, f.Values[0])
+	}
+	value, err := strconv.ParseFloat(recordValue, 64)
+	if err != nil {
+		return false
+	}
+	return ((value >= f.Min.Value() && f.Min.IsInclusive()) || (value > f.Min.Value() && !f.Min.IsInclusive())) &&
+		((value <= f.Max.Value() && f.Max.IsInclusive()) || (value < f.Max.Value() && !f.Max.IsInclusive()))
 }
 
-// AddFilter adds a set of criteria that records will have to match.
-func (f *FacetEngine) AddFilter(facetGroupName string, facetName string, min Range, max Range) error {
-	if f.query.Filters == nil {
-		f.query.Filters = []filter{}
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
-	if strings.TrimSpace(facetGroupName) == "" {
-		return fmt.Errorf("must specify facetgroup name")
+	return false
+}
+
+// AddFilter adds a set of criteria that records will have to match.  Returns the name of the filter.
+func (f *FacetEngine) AddFilter(facetGroupName string, facetName string, min Range, max Range) (string, error) {
+	return f.AddExpression(NewFilter(facetGroupName, facetName, min, max))
+}
+
+// AddOrGroup adds criteria where records will have to match at least one of the expressions.  Returns the name of the filter.
+func (f *FacetEngine) AddOrGroup(expressions ...*Expression) (string, error) {
+	return f.AddExpression(Or(expressions...))
+}
+
+// AddNot adds criteria that records must not match.  Returns the name of the filter.
+func (f *FacetEngine) AddNot(expression *Expression) (string, error) {
+	return f.AddExpression(Not(expression))
+}
+
+// AddExpression adds a tree of criteria that records will have to match.  If the expression has no name one is
+// generated.  The expression is copied rather than named in place, so it can be added again.  Returns the name of the
+// filter.
+func (f *FacetEngine) AddExpression(expression *Expression) (string, error) {
+	if err := expression.Validate(); err != nil {
+		return "", err
+	}
+	named := *expression
+	if strings.TrimSpace(named.Name) == "" {
+		f.query.filterID++
+		named.Name = fmt.Sprintf("filter-%d", f.query.filterID)
+		for f.indexOfFilter(named.Name) != -1 {
+			f.query.filterID++
+			named.Name = fmt.Sprintf("filter-%d", f.query.filterID)
+		}
+	} else if f.indexOfFilter(named.Name) != -1 {
+		return "", NewError(ErrInvalidFilter, "filter %s already exists", named.Name)
+	}
+	f.query.Root.Children = append(f.query.Root.Children, &named)
+	return named.Name, nil
+}
+
+// RemoveFilter remove a single filter by the name returned when it was added.
+func (f *FacetEngine) RemoveFilter(name string) error {
+	i := f.indexOfFilter(name)
+	if i == -1 {
+		return NewError(ErrUnknownFilter, "no filter named %s", name)
+	}
+	children := f.query.Root.Children
+	f.query.Root.Children = append(children[:i:i], children[i+1:]...)
+	return nil
+}
+
+// UpdateFilter replace the criteria of a named filter, keeping its name and position.  Like AddExpression the
+// expression is copied.
+func (f *FacetEngine) UpdateFilter(name string, expression *Expression) error {
+	i := f.indexOfFilter(name)
+	if i == -1 {
+		return NewError(ErrUnknownFilter, "no filter named %s", name)
 	}
-	if strings.TrimSpace(facetName) == "" {
-		return fmt.Errorf("must specify facet name")
+	if err := expression.Validate(); err != nil {
+		return err
 	}
-	f.query.Filters = append(f.query.Filters, filter{
-		FacetGroupName: facetGroupName,
-		FacetName:      facetName,
-		Min:            min,
-		Max:            max,
-	})
+	named := *expression
+	named.Name = name
+	f.query.Root.Children[i] = &named
 	return nil
 }
 
+// ListFilters return the filters in the order they were added.
+func (f *FacetEngine) ListFilters() []*Expression {
+	filters := make([]*Expression, len(f.query.Root.Children))
+	copy(filters, f.query.Root.Children)
+	return filters
+}
+
+func (f *FacetEngine) indexOfFilter(name string) int {
+	for i, child := range f.query.Root.Children {
+		if child.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
 // Range represents min and max bounds inclusive or exclusive
 type Range interface {
 	IsInclusive() bool
@@ -146,20 +503,23 @@ func (e exclusive) Value() float64 {
 
 // ClearFilters remove all the query state
 func (f *FacetEngine) ClearFilters() {
-	f.query = &Query{}
+	f.query = newQuery()
 	f.resetAllIds()
 }
 
 func (f *FacetEngine) resetAllIds() {
-	f.ids = NewSet()
-	for _, id := range f.allIds.ToArray() {
-		f.ids.Add(id)
-	}
+	f.ids = newSetOf(f.dictionary, f.allIds.bitmap.Clone())
+}
+
+// SetDisjunctive when enabled, the facets returned from Query are computed with every filter applied except the ones on
+// that facet, so that a facet shows the values that widening its own filter would give.
+func (f *FacetEngine) SetDisjunctive(enabled bool) {
+	f.disjunctive = enabled
 }
 
 // Query filter the records and return ids that match the filters
 func (f FacetEngine) Query() ([]string, map[string]*FacetGroup, error) {
-	if len(f.query.Filters) == 0 {
+	if len(f.query.Root.Children) == 0 {
 		facetGroups, err := f.GetFacets()
 		f.resetAllIds()
 		return f.allIds.ToArray(), facetGroups, err
@@ -167,160 +527,383 @@ func (f FacetEngine) Query() ([]string, map[string]*FacetGroup, error) {
 	if f.ids.Len() == 0 {
 		return []string{}, map[string]*FacetGroup{}, nil
 	}
-	listOfMaps := make([]map[string]bool, len(f.query.Filters))
-	f.ids = NewSet()
-	for i, filter := range f.query.Filters {
-		key := fmt.Sprintf("%s - %s", filter.FacetGroupName, filter.FacetName)
-		if records, ok := f.RecordLookup[key]; ok {
-			listOfMaps[i] = toStringMap(records, filter)
+	f.ids = newSetOf(f.dictionary, f.evaluate(f.query.Root))
+	facetGroups, err := f.GetFacets()
+	if err == nil && f.disjunctive {
+		err = f.disjunctiveFacets(facetGroups)
+	}
+	return f.ids.ToArray(), facetGroups, err
+}
+
+// disjunctiveFacets replace each filtered facet in facetGroups with one computed without the filters on that facet.
+func (f FacetEngine) disjunctiveFacets(facetGroups map[string]*FacetGroup) error {
+	excluded := map[FacetKey]map[int]bool{}
+	for i, child := range f.query.Root.Children {
+		if key, ok := child.facetOf(); ok {
+			key = f.facetPath.key(key.Group, key.Facet)
+			if excluded[key] == nil {
+				excluded[key] = map[int]bool{}
+			}
+			excluded[key][i] = true
 		}
 	}
-	for k := range listOfMaps[0] {
-		inAll := true
-		for i := 1; i < len(listOfMaps); i++ {
-			_, inThis := listOfMaps[i][k]
-			// todo should just break when the first false happens
-			inAll = inThis && inAll
+	for key, indexes := range excluded {
+		root := And()
+		for i, child := range f.query.Root.Children {
+			if !indexes[i] {
+				root.Children = append(root.Children, child)
+			}
+		}
+		f.ids = newSetOf(f.dictionary, f.evaluate(root))
+		groups, err := f.GetFacets()
+		if err != nil {
+			return err
+		}
+		var facet *Facet
+		group, ok := groups[key.Group]
+		if ok {
+			facet = group.Facets[key.Facet]
+		}
+		facetGroup, ok := facetGroups[key.Group]
+		if !ok {
+			if facet == nil {
+				continue
+			}
+			facetGroup = &FacetGroup{
+				Name:   group.Name,
+				Facets: map[string]*Facet{},
+			}
+			facetGroups[key.Group] = facetGroup
 		}
-		if inAll {
-			f.ids.Add(k)
+		if facet == nil {
+			delete(facetGroup.Facets, key.Facet)
+		} else {
+			facetGroup.Facets[key.Facet] = facet
 		}
 	}
-	facetGroups, err := f.GetFacets()
-	return f.ids.ToArray(), facetGroups, err
+	return nil
 }
 
-func toStringMap(records []*Record, filter filter) map[string]bool {
-	results := map[string]bool{}
-	for _, record := range records {
-		// this parse error is guaranteed not to happen elsewhere.
-		value, _ := strconv.ParseFloat(record.Value, 64)
-		if ((value >= filter.Min.Value() && filter.Min.IsInclusive()) || (value > filter.Min.Value() && !filter.Min.IsInclusive())) &&
-			((value <= filter.Max.Value() && filter.Max.IsInclusive()) || (value < filter.Max.Value() && !filter.Max.IsInclusive())) {
-			results[record.ID] = true
+// evaluate the expression against the index and return the ordinals of the ids that match.
+func (f FacetEngine) evaluate(expression *Expression) *Bitmap {
+	switch expression.Operator {
+	case OpAnd:
+		var results *Bitmap
+		for _, child := range expression.Children {
+			matches := f.evaluate(child)
+			if results == nil {
+				results = matches
+				continue
+			}
+			results = results.And(matches)
 		}
+		if results == nil {
+			results = f.allIds.bitmap.Clone()
+		}
+		return results
+	case OpOr:
+		results := NewBitmap()
+		for _, child := range expression.Children {
+			results = results.Or(f.evaluate(child))
+		}
+		return results
+	case OpNot:
+		return f.allIds.bitmap.AndNot(f.evaluate(expression.Children[0]))
 	}
-	return results
+	if column, ok := f.RecordLookup[f.facetPath.key(expression.Filter.FacetGroupName, expression.Filter.FacetName)]; ok {
+		return column.Matching(*expression.Filter)
+	}
+	return NewBitmap()
 }
 
 // Initialize take an json string representation of an array of objects and turn them in to facets.
 // facetPaths is a query of which facets in the data to use to create facets.
 func (f *FacetEngine) Initialize(jsonData string, facetPath *FacetPath) (map[string]*FacetGroup, error) {
 	if strings.TrimSpace(jsonData) == "" {
-		return f.GetFacets()
+		return f.InitializeObjects(nil, facetPath)
 	}
 	var genericObjects []map[string]interface{}
 	err := json.Unmarshal([]byte(jsonData), &genericObjects)
 	if err != nil {
+		return nil, ToError(err)
+	}
+	return f.InitializeObjects(genericObjects, facetPath)
+}
+
+// InitializeObjects turn already decoded objects in to facets, see Initialize.  Any records already loaded are replaced
+// and filters are cleared.  On error the engine is left as it was.
+func (f *FacetEngine) InitializeObjects(genericObjects []map[string]interface{}, facetPath *FacetPath) (map[string]*FacetGroup, error) {
+	if facetPath != nil {
+		if err := facetPath.validate(); err != nil {
+			return nil, err
+		}
+	}
+	fresh := newFacetEngine()
+	fresh.facetPath = facetPath
+	fresh.paths = f.paths
+	if err := fresh.ingest(genericObjects, false); err != nil {
 		return nil, err
 	}
-	f.genericObjects = genericObjects
+	f.RecordLookup = fresh.RecordLookup
+	f.dictionary = fresh.dictionary
+	f.allIds = fresh.allIds
+	f.records = fresh.records
+	f.paths = fresh.paths
+	f.groupLabels = fresh.groupLabels
 	f.facetPath = facetPath
-
-	facetGroups, err := f.GetFacets()
-	f.resetAllIds()
 	f.initialized = true
-	return facetGroups, err
+	f.ClearFilters()
+	return f.GetFacets()
 }
 
-// GetFacets return a list of facets for the list of ids.  If ids is nil, return all possible facets.
+// GetFacets return the facets of all the records, filters are not applied.  Query returns the facets of the records
+// matching the filters.
 func (f *FacetEngine) GetFacets() (map[string]*FacetGroup, error) {
+	return f.aggregate(f.ordinalsOf(f.ids)), nil
+}
+
+// ordinalsOf the ids in the set, which may have been made with its own dictionary rather than the engine's.
+func (f *FacetEngine) ordinalsOf(ids *Set) *Bitmap {
+	if ids.dictionary == f.dictionary {
+		return ids.bitmap
+	}
+	results := NewBitmap()
+	for _, id := range ids.ToArray() {
+		if ordinal, ok := f.dictionary.ordinals[id]; ok {
+			results.Add(ordinal)
+		}
+	}
+	return results
+}
+
+// aggregate compute the facets of the records with the ordinals from the index.  Like Statistics, each value a record
+// has for a facet is counted once.
+func (f *FacetEngine) aggregate(ordinals *Bitmap) map[string]*FacetGroup {
 	facetGroups := map[string]*FacetGroup{}
-	for _, genericObject := range f.genericObjects {
-		idPaths := f.facetPath.IDDotNotation
-		if idPaths == "" {
-			idPaths = "id"
+	groupOrdinals := map[string]*Bitmap{}
+	for _, column := range f.RecordLookup {
+		facet := &Facet{
+			Name:        column.FacetLabel,
+			Categorical: column.Categorical,
+			Values:      NewSet(),
+			Counts:      map[string]int{},
 		}
-		id := getAtPathString(genericObject, strings.Split(idPaths, "."))
-		if strings.TrimSpace(id) == "" {
-			return nil, fmt.Errorf("found record with no id")
+		facetOrdinals := NewBitmap()
+		numbers := []float64{}
+		seen := map[entry]bool{}
+		for i, ordinal := range column.Ordinals {
+			e := entry{ordinal: ordinal, value: column.Values[i]}
+			if !ordinals.Contains(ordinal) || seen[e] {
+				continue
+			}
+			seen[e] = true
+			facetOrdinals.Add(ordinal)
+			facet.Counts[column.Values[i]]++
+			if !column.Categorical {
+				numbers = append(numbers, column.Numbers[i])
+			}
 		}
-		if f.initialized && !f.ids.Contains(id) {
+		facet.Count = facetOrdinals.Len()
+		if facet.Count == 0 {
 			continue
 		}
-		f.allIds.Add(id)
-		arrayPaths := strings.Split(f.facetPath.ArrayDotNotation, ".")
-		namePaths := strings.Split(f.facetPath.NameFieldDotNotation, ".")
-		nameMetaPaths := strings.Split(f.facetPath.NameMetaDotNotation, ".")
-		valuePaths := strings.Split(f.facetPath.ValueMapDotNotation, ".")
-		arraysObject := getAtPathArray(genericObject, arrayPaths)
-		for _, object := range arraysObject {
-			o := object.(map[string]interface{})
-			name := getAtPathString(o, namePaths)
-			nameMeta := getAtPathString(o, nameMetaPaths)
-			values := getAtPathMap(o, valuePaths)
-			key := strings.ToLower(fmt.Sprintf("%s (%s)", name, nameMeta))
-			if len(values) == 0 || strings.TrimSpace(name) == "" || strings.TrimSpace(nameMeta) == "" {
-				continue
-			}
-			if _, ok := facetGroups[key]; !ok {
-				facetGroups[key] = &FacetGroup{
-					Name:   key,
-					Facets: map[string]*Facet{},
-				}
+		for value := range facet.Counts {
+			facet.Values.Add(value)
+		}
+		if !column.Categorical {
+			facet.Statistics = newStatistics(numbers, f.percentiles)
+		}
+		facetGroup, ok := facetGroups[column.Group]
+		if !ok {
+			facetGroup = &FacetGroup{
+				Name:   column.GroupLabel,
+				Facets: map[string]*Facet{},
 			}
+			facetGroups[column.Group] = facetGroup
+			groupOrdinals[column.Group] = NewBitmap()
+		}
+		facetGroup.Facets[column.Facet] = facet
+		groupOrdinals[column.Group] = groupOrdinals[column.Group].Or(facetOrdinals)
+	}
+	for name, facetGroup := range facetGroups {
+		facetGroup.Count = groupOrdinals[name].Len()
+	}
+	return facetGroups
+}
 
-			for k, v := range values {
-				facetKey := strings.ToLower(k)
-				f.RecordLookup.Add(fmt.Sprintf("%s - %s", key, facetKey), &Record{
-					ID:    id,
-					Value: v,
-				})
-				facetGroup := facetGroups[key]
-				if _, ok := facetGroup.Facets[facetKey]; !ok {
-					facetGroup.Facets[facetKey] = &Facet{
-						Name:   facetKey,
-						Values: NewSet(),
-					}
-				}
+// extractedValue is one value of a facet found in a record.  group and facet are the keys, the labels are the names as
+// they were found.
+type extractedValue struct {
+	group       string
+	facet       string
+	groupLabel  string
+	facetLabel  string
+	value       string
+	number      float64
+	categorical bool
+}
 
-				_, err := strconv.ParseFloat(v, 64)
-				if err != nil {
-					return nil, err
+// recordID find the id of the record using the facet path.
+func (f *FacetEngine) recordID(genericObject map[string]interface{}) (string, error) {
+	idPaths := f.facetPath.IDDotNotation
+	if idPaths == "" {
+		idPaths = "id"
+	}
+	id := getAtPathString(genericObject, f.path(idPaths))
+	if strings.TrimSpace(id) == "" {
+		return "", &Error{Code: ErrMissingID, Message: "found record with no id", Path: idPaths}
+	}
+	return id, nil
+}
+
+// extract find all the facet values in the record using each rule of the facet path.
+func (f *FacetEngine) extract(id string, genericObject map[string]interface{}) ([]*extractedValue, error) {
+	results := []*extractedValue{}
+	for _, rule := range f.facetPath.rules() {
+		values, err := f.extractRule(id, genericObject, rule)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, values...)
+	}
+	for _, field := range f.facetPath.Fields {
+		values, err := f.extractField(id, genericObject, field)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, values...)
+	}
+	return results, nil
+}
+
+// extractField find the values of a field facet in the record.  Objects are not values and are skipped.
+func (f *FacetEngine) extractField(id string, genericObject map[string]interface{}, field *FieldFacet) ([]*extractedValue, error) {
+	results := []*extractedValue{}
+	groupLabel, facetLabel := field.name()
+	group, facet := f.facetPath.fold(groupLabel), f.facetPath.fold(facetLabel)
+	found := f.path(field.DotNotation).find(genericObject)
+	values := flatten(found)
+	// a single value that isn't in an array is reported at the field's path, otherwise with its index.
+	_, inArray := firstOf(found).([]interface{})
+	indexed := inArray || len(found) > 1
+	for i, v := range values {
+		switch v.(type) {
+		case nil, map[string]interface{}, []interface{}:
+			continue
+		}
+		value := &extractedValue{
+			group:       group,
+			facet:       facet,
+			groupLabel:  groupLabel,
+			facetLabel:  facetLabel,
+			value:       formatValue(v),
+			categorical: f.facetPath.isCategorical(group, facet),
+		}
+		if !value.categorical {
+			number, err := strconv.ParseFloat(value.value, 64)
+			if err != nil {
+				path := field.DotNotation
+				if indexed {
+					path = indexPath(path, i)
 				}
-				facetGroup.Facets[facetKey].Values.Add(v)
+				return nil, &Error{Code: ErrInvalidNumber, Message: err.Error(), RecordID: id, Path: path}
 			}
+			value.number = number
 		}
+		results = append(results, value)
 	}
-	return facetGroups, nil
+	return results, nil
 }
 
-func getAtPathArray(data map[string]interface{}, path []string) []interface{} {
-	obj := getAtPath(data, path)
-	if obj == nil {
-		return nil
+// formatValue turn a json string, number or bool in to a facet value.  Numbers are written in full, never with an
+// exponent, so 1234567 is "1234567" rather than "1.234567e+06".
+func formatValue(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
 	}
-	return obj.([]interface{})
+	return fmt.Sprintf("%v", value)
 }
 
-func getAtPathString(data map[string]interface{}, path []string) string {
-	obj := getAtPath(data, path)
-	if obj == nil {
-		return ""
+// extractRule find the facet values in the record for one rule.
+func (f *FacetEngine) extractRule(id string, genericObject map[string]interface{}, rule *ExtractionRule) ([]*extractedValue, error) {
+	results := []*extractedValue{}
+	namePaths := f.path(rule.NameFieldDotNotation)
+	nameMetaPaths := f.path(rule.NameMetaDotNotation)
+	valuePaths := f.path(rule.ValueMapDotNotation)
+	for i, object := range getAtPathValues(genericObject, f.path(rule.ArrayDotNotation)) {
+		o, ok := object.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := getAtPathString(o, namePaths)
+		nameMeta := getAtPathString(o, nameMetaPaths)
+		values := getAtPathMap(o, valuePaths)
+		label, ok := rule.groupName(name, nameMeta)
+		if len(values) == 0 || !ok {
+			continue
+		}
+		key := f.facetPath.fold(label)
+		for k, v := range values {
+			facetKey := f.facetPath.fold(k)
+			value := &extractedValue{
+				group:       key,
+				facet:       facetKey,
+				groupLabel:  label,
+				facetLabel:  k,
+				value:       v,
+				categorical: f.facetPath.isCategorical(key, facetKey),
+			}
+			if !value.categorical {
+				number, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return nil, &Error{
+						Code:     ErrInvalidNumber,
+						Message:  err.Error(),
+						RecordID: id,
+						Path:     valuePath(rule, i, k),
+					}
+				}
+				value.number = number
+			}
+			results = append(results, value)
+		}
 	}
-	return obj.(string)
+	return results, nil
 }
 
-func getAtPathMap(data map[string]interface{}, path []string) map[string]string {
-	obj := getAtPath(data, path)
-	if obj == nil {
-		return nil
+// path return the compiled path, paths are checked when the facet path is validated so one that doesn't compile finds
+// nothing.
+func (f *FacetEngine) path(path string) selector {
+	if f.paths == nil {
+		f.paths = map[string]selector{}
 	}
-	values := map[string]string{}
-	for key, value := range obj.(map[string]interface{}) {
-		strKey := fmt.Sprintf("%v", key)
-		strValue := fmt.Sprintf("%v", value)
-		values[strKey] = strValue
+	compiled, ok := f.paths[path]
+	if !ok {
+		var err error
+		if compiled, err = compilePath(path); err != nil {
+			compiled = dotPath(nil)
+		}
+		f.paths[path] = compiled
 	}
-	return values
+	return compiled
 }
 
-func getAtPath(data map[string]interface{}, path []string) interface{} {
-	if len(path) == 1 {
-		return data[path[0]]
+// indexPath add the index of a value found to the path it was found at.
+func indexPath(path string, i int) string {
+	if isJSONPath(path) {
+		return fmt.Sprintf("%s[%d]", path, i)
 	}
-	if data[path[0]] == nil {
-		return nil
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// valuePath describe where a rule found the value with key in the value map of the i'th object.
+func valuePath(rule *ExtractionRule, i int, key string) string {
+	if isJSONPath(rule.ArrayDotNotation) || isJSONPath(rule.ValueMapDotNotation) {
+		valueMap := strings.TrimPrefix(strings.TrimSpace(rule.ValueMapDotNotation), "$")
+		if !isJSONPath(rule.ValueMapDotNotation) {
+			valueMap = "." + valueMap
+		}
+		return fmt.Sprintf("%s[%d]%s.%s", rule.ArrayDotNotation, i, valueMap, key)
 	}
-	return getAtPath(data[path[0]].(map[string]interface{}), path[1:])
+	return fmt.Sprintf("%s.%d.%s.%s", rule.ArrayDotNotation, i, rule.ValueMapDotNotation, key)
 }
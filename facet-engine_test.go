@@ -1,7 +1,12 @@
-package main
+package facetengine
 
 import (
 	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -51,6 +56,9 @@ func TestBadNumber(t *testing.T) {
 	_, _, err := NewFacetEngine("["+object9+"]", defaultFacetPath)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "strconv.ParseFloat")
+	require.Equal(t, ErrInvalidNumber, ToError(err).Code)
+	require.NotEmpty(t, ToError(err).RecordID)
+	require.NotEmpty(t, ToError(err).Path)
 }
 
 func TestQueryInclusiveExclusive(t *testing.T) {
@@ -84,6 +92,261 @@ func TestQueryAnd(t *testing.T) {
 	require.ElementsMatch(t, []string{"1"}, listOfIds)
 }
 
+func TestQueryOr(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+"]", defaultFacetPath)
+	_, err := facetEngine.AddOrGroup(
+		NewFilter("shaft (screwthread)", "pitch", Inclusive(1.5), Inclusive(1.5)),
+		NewFilter("total-area (hex-cylinder)", "diameter", Inclusive(16), Inclusive(16)),
+	)
+	require.Nil(t, err)
+	listOfIds, _, _ := facetEngine.Query()
+	require.ElementsMatch(t, []string{"1", "2"}, listOfIds)
+}
+
+func TestQueryNot(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+"]", defaultFacetPath)
+	_, err := facetEngine.AddNot(NewFilter("shaft (screwthread)", "pitch", Exclusive(2), Inclusive(100)))
+	require.Nil(t, err)
+	listOfIds, _, _ := facetEngine.Query()
+	require.ElementsMatch(t, []string{"1", "2"}, listOfIds)
+
+	facetEngine.ClearFilters()
+	_, err = facetEngine.AddNot(NewFilter("shaft (screwthread)", "pitch", Inclusive(1), Inclusive(2)))
+	require.Nil(t, err)
+	listOfIds, _, _ = facetEngine.Query()
+	require.ElementsMatch(t, []string{"2"}, listOfIds)
+}
+
+func TestQueryNested(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	_, err := facetEngine.AddExpression(Or(
+		And(
+			NewFilter("total-area (hex-cylinder)", "diameter", Inclusive(16), Inclusive(16)),
+			Not(NewFilter("total-area (hex-cylinder)", "weird", Inclusive(1), Inclusive(1))),
+		),
+		NewFilter("head (hex-cylinder)", "height", Inclusive(0), Inclusive(10)),
+	))
+	require.Nil(t, err)
+	listOfIds, _, _ := facetEngine.Query()
+	require.ElementsMatch(t, []string{"1"}, listOfIds)
+	facetEngine.AddFilter("total-area (hex-cylinder)", "height", Exclusive(20), Inclusive(100))
+	listOfIds, _, _ = facetEngine.Query()
+	require.ElementsMatch(t, []string{}, listOfIds)
+}
+
+func TestBadExpression(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine(readmeExample, readmeFacetPath)
+	_, err := facetEngine.AddExpression(nil)
+	require.Error(t, err)
+	_, err = facetEngine.AddOrGroup()
+	require.Error(t, err)
+	_, err = facetEngine.AddNot(NewFilter(" ", "side", Inclusive(0), Inclusive(1)))
+	require.Error(t, err)
+	_, err = facetEngine.AddExpression(And(NewFilter("area (cube)", "side", nil, Inclusive(1))))
+	require.Error(t, err)
+}
+
+func TestNamedFilters(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine(readmeExample, readmeFacetPath)
+	small, err := facetEngine.AddFilter("area (cube)", "side", Inclusive(0), Inclusive(12))
+	require.Nil(t, err)
+	large, err := facetEngine.AddFilter("area (cube)", "side", Inclusive(8), Inclusive(25))
+	require.Nil(t, err)
+	require.NotEqual(t, small, large)
+	listOfIds, _, _ := facetEngine.Query()
+	require.ElementsMatch(t, []string{"record 1"}, listOfIds)
+
+	require.Nil(t, facetEngine.RemoveFilter(small))
+	listOfIds, _, _ = facetEngine.Query()
+	require.ElementsMatch(t, []string{"record 1", "record 2"}, listOfIds)
+	require.Error(t, facetEngine.RemoveFilter(small))
+
+	require.Nil(t, facetEngine.UpdateFilter(large, NewFilter("area (cube)", "side", Inclusive(15), Inclusive(25))))
+	listOfIds, _, _ = facetEngine.Query()
+	require.ElementsMatch(t, []string{"record 2"}, listOfIds)
+	require.Error(t, facetEngine.UpdateFilter(small, NewFilter("area (cube)", "side", Inclusive(15), Inclusive(25))))
+	require.Error(t, facetEngine.UpdateFilter(large, NewFilter("area (cube)", " ", Inclusive(15), Inclusive(25))))
+
+	filters := facetEngine.ListFilters()
+	require.Equal(t, 1, len(filters))
+	require.Equal(t, large, filters[0].Name)
+	require.Equal(t, 15.0, filters[0].Filter.Min.Value())
+}
+
+func TestFilterNames(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine(readmeExample, readmeFacetPath)
+	named := NewFilter("area (cube)", "side", Inclusive(0), Inclusive(12))
+	named.Name = "filter-1"
+	name, err := facetEngine.AddExpression(named)
+	require.Nil(t, err)
+	require.Equal(t, "filter-1", name)
+	name, err = facetEngine.AddFilter("area (cube)", "side", Inclusive(0), Inclusive(12))
+	require.Nil(t, err)
+	require.Equal(t, "filter-2", name)
+	duplicate := NewFilter("area (cube)", "side", Inclusive(0), Inclusive(12))
+	duplicate.Name = "filter-2"
+	_, err = facetEngine.AddExpression(duplicate)
+	require.Error(t, err)
+}
+
+func TestAddExpressionTwice(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine(readmeExample, readmeFacetPath)
+	expression := NewFilter("area (cube)", "side", Inclusive(0), Inclusive(12))
+	first, err := facetEngine.AddExpression(expression)
+	require.Nil(t, err)
+	second, err := facetEngine.AddExpression(expression)
+	require.Nil(t, err)
+	require.NotEqual(t, first, second)
+	require.Equal(t, "", expression.Name)
+	require.Nil(t, facetEngine.UpdateFilter(first, expression))
+	require.Equal(t, "", expression.Name)
+	filters := facetEngine.ListFilters()
+	require.Equal(t, []string{first, second}, []string{filters[0].Name, filters[1].Name})
+}
+
+func TestDisjunctive(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine(readmeExample, readmeFacetPath)
+	facetEngine.AddFilter("area (cube)", "side", Inclusive(8), Inclusive(12))
+	_, facetGroups, _ := facetEngine.Query()
+	require.Equal(t, map[string]int{"10": 1}, facetGroups["area (cube)"].Facets["side"].Counts)
+
+	facetEngine.SetDisjunctive(true)
+	listOfIds, facetGroups, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"record 1"}, listOfIds)
+	require.Equal(t, 1, facetGroups["area (cube)"].Count)
+	require.Equal(t, map[string]int{"10": 1, "20": 1}, facetGroups["area (cube)"].Facets["side"].Counts)
+}
+
+func TestDisjunctiveOtherFacets(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	facetEngine.SetDisjunctive(true)
+	facetEngine.AddFilter("total-area (hex-cylinder)", "diameter", Inclusive(16), Inclusive(16))
+	facetEngine.AddOrGroup(
+		NewFilter("total-area (hex-cylinder)", "weird", Inclusive(0), Inclusive(0)),
+		NewFilter("total-area (hex-cylinder)", "weird", Inclusive(5), Inclusive(5)),
+	)
+	listOfIds, facetGroups, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.Empty(t, listOfIds)
+	totalArea := facetGroups["total-area (hex-cylinder)"]
+	require.Equal(t, 0, totalArea.Count)
+	require.Nil(t, totalArea.Facets["diameter"])
+	require.Equal(t, map[string]int{"1": 2}, totalArea.Facets["weird"].Counts)
+	require.Nil(t, totalArea.Facets["height"])
+
+	facetEngine.ClearFilters()
+	facetEngine.AddFilter("shaft (screwthread)", "pitch", Inclusive(0), Inclusive(10))
+	_, facetGroups, err = facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, map[string]int{"15": 1}, facetGroups["total-area (hex-cylinder)"].Facets["diameter"].Counts)
+	require.Equal(t, map[string]int{"1.5": 1}, facetGroups["shaft (screwthread)"].Facets["pitch"].Counts)
+}
+
+func TestExpressionJSON(t *testing.T) {
+	expression := Or(
+		NewFilter("area (cube)", "side", Inclusive(8), Exclusive(12)),
+		Not(NewFilter("area (cube)", "side", Exclusive(15), Inclusive(25))),
+	)
+	data, err := json.Marshal(expression)
+	require.Nil(t, err)
+	require.Equal(t, `{"or":[{"facetGroupName":"area (cube)","facetName":"side","inclusiveMin":true,"min":8,"max":12},{"not":{"facetGroupName":"area (cube)","facetName":"side","min":15,"inclusiveMax":true,"max":25}}]}`, string(data))
+	decoded := &Expression{}
+	err = json.Unmarshal(data, decoded)
+	require.Nil(t, err)
+	require.Equal(t, expression, decoded)
+	require.Error(t, json.Unmarshal([]byte(`{}`), decoded))
+	require.Error(t, json.Unmarshal([]byte(`{"and":[null]}`), decoded))
+}
+
+var categoricalFacetPath = &FacetPath{
+	ArrayDotNotation:     "bounds",
+	NameFieldDotNotation: "name",
+	NameMetaDotNotation:  "boundingType.name",
+	ValueMapDotNotation:  "boundingType.measurements",
+	CategoricalFacets:    []string{"Material"},
+}
+
+var categoricalExample = `[
+	{"id": "1", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "1.5", "material": "steel"}}}]},
+	{"id": "2", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "2", "material": "stainless steel"}}}]},
+	{"id": "3", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "2", "material": "brass"}}}]},
+	{"id": "4", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "1"}}}]}
+]`
+
+func TestCategoricalFacets(t *testing.T) {
+	_, facetGroups, err := NewFacetEngine(categoricalExample, categoricalFacetPath)
+	require.Nil(t, err)
+	material := facetGroups["shaft (screwthread)"].Facets["material"]
+	require.True(t, material.Categorical)
+	require.Nil(t, material.Statistics)
+	require.Equal(t, map[string]int{"steel": 1, "stainless steel": 1, "brass": 1}, material.Counts)
+	pitch := facetGroups["shaft (screwthread)"].Facets["pitch"]
+	require.False(t, pitch.Categorical)
+	require.Equal(t, 4, pitch.Statistics.Count)
+
+	_, _, err = NewFacetEngine(categoricalExample, defaultFacetPath)
+	require.Error(t, err)
+}
+
+func TestCategoricalFilters(t *testing.T) {
+	testExpression(t, Equals("shaft (screwthread)", "material", "steel"), []string{"1"})
+	testExpression(t, In("shaft (screwthread)", "material", "steel", "brass"), []string{"1", "3"})
+	testExpression(t, NotIn("shaft (screwthread)", "material", "steel", "brass"), []string{"2"})
+	testExpression(t, Prefix("shaft (screwthread)", "material", "st"), []string{"1", "2"})
+	testExpression(t, NewFilter("shaft (screwthread)", "material", Inclusive(0), Inclusive(100)), []string{})
+	testExpression(t, And(
+		NewFilter("shaft (screwthread)", "pitch", Inclusive(2), Inclusive(2)),
+		Not(Equals("shaft (screwthread)", "material", "brass")),
+	), []string{"2"})
+	testExpression(t, Equals("shaft (screwthread)", "pitch", "2"), []string{"2", "3"})
+}
+
+func TestCategoricalNotNumeric(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine(categoricalExample, categoricalFacetPath)
+	_, err := facetEngine.Histogram("shaft (screwthread)", "material", FixedCount(2))
+	require.Error(t, err)
+	_, err = facetEngine.Statistics("shaft (screwthread)", "material")
+	require.Error(t, err)
+}
+
+func TestValueFilters(t *testing.T) {
+	expression, err := NewValueFilter("group", "facet", "notIn", "a", "b")
+	require.Nil(t, err)
+	require.Equal(t, NotIn("group", "facet", "a", "b"), expression)
+	_, err = NewValueFilter("group", "facet", "like", "a")
+	require.Error(t, err)
+	require.Error(t, In("group", "facet").Validate())
+	require.Error(t, newValueFilter("group", "facet", MatchEquals, []string{"a", "b"}).Validate())
+	require.Error(t, newValueFilter("group", "facet", Match(99), []string{"a"}).Validate())
+}
+
+func TestValueFilterJSON(t *testing.T) {
+	expression := And(
+		Equals("group", "facet", "a"),
+		In("group", "facet", "a", "b"),
+		NotIn("group", "facet", "c"),
+		Prefix("group", "facet", ""),
+	)
+	data, err := json.Marshal(expression)
+	require.Nil(t, err)
+	require.Equal(t, `{"and":[{"facetGroupName":"group","facetName":"facet","equals":"a"},{"facetGroupName":"group","facetName":"facet","in":["a","b"]},{"facetGroupName":"group","facetName":"facet","notIn":["c"]},{"facetGroupName":"group","facetName":"facet","prefix":""}]}`, string(data))
+	decoded := &Expression{}
+	err = json.Unmarshal(data, decoded)
+	require.Nil(t, err)
+	require.Equal(t, expression, decoded)
+}
+
+func testExpression(t *testing.T, expression *Expression, expected []string) {
+	facetEngine, _, err := NewFacetEngine(categoricalExample, categoricalFacetPath)
+	require.Nil(t, err)
+	_, err = facetEngine.AddExpression(expression)
+	require.Nil(t, err)
+	listOfIds, _, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.ElementsMatch(t, expected, listOfIds)
+}
+
 func testFilter(t *testing.T, example string, facetGroupName string, facetName string, min Range, max Range, expected []string) {
 	facetEngine, _, err := NewFacetEngine(example, readmeFacetPath)
 	if err != nil {
@@ -120,13 +383,45 @@ func TestMarshalSet(t *testing.T) {
 	if err != nil {
 		panic(err)
 	}
-	require.Equal(t, "{\"area (cube)\":{\"name\":\"area (cube)\",\"facets\":{\"side\":{\"name\":\"side\",\"values\":[\"10\",\"20\"]}}}}", string(data))
+	require.Equal(t, "{\"area (cube)\":{\"name\":\"area (cube)\",\"count\":2,\"facets\":{\"side\":{\"name\":\"side\",\"count\":2,\"values\":[\"10\",\"20\"],\"counts\":{\"10\":1,\"20\":1},\"statistics\":{\"count\":2,\"min\":10,\"max\":20,\"sum\":30,\"mean\":15,\"stdDev\":5}}}}}", string(data))
 	decoded := map[string]*FacetGroup{}
 	err = json.Unmarshal(data, &decoded)
 	if err != nil {
 		panic(err)
 	}
 	require.ElementsMatch(t, []string{"10", "20"}, decoded["area (cube)"].Facets["side"].Values.ToArray())
+	require.Equal(t, map[string]int{"10": 1, "20": 1}, decoded["area (cube)"].Facets["side"].Counts)
+}
+
+func TestCounts(t *testing.T) {
+	facetEngine, facetGroups, err := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	require.Nil(t, err)
+	totalArea := facetGroups["total-area (hex-cylinder)"]
+	require.Equal(t, 3, totalArea.Count)
+	require.Equal(t, 3, totalArea.Facets["diameter"].Count)
+	require.Equal(t, map[string]int{"15": 1, "16": 2}, totalArea.Facets["diameter"].Counts)
+	require.Equal(t, 2, totalArea.Facets["weird"].Count)
+	require.Equal(t, 1, facetGroups["shaft (screwthread)"].Count)
+
+	facetEngine.AddFilter("total-area (hex-cylinder)", "diameter", Inclusive(16), Inclusive(16))
+	_, facetGroups, err = facetEngine.Query()
+	require.Nil(t, err)
+	totalArea = facetGroups["total-area (hex-cylinder)"]
+	require.Equal(t, 2, totalArea.Count)
+	require.Equal(t, map[string]int{"16": 2}, totalArea.Facets["diameter"].Counts)
+	require.Nil(t, facetGroups["shaft (screwthread)"])
+}
+
+func TestCountsOncePerRecord(t *testing.T) {
+	_, facetGroups, err := NewFacetEngine(`[{"id":"1","bounds":[
+		{"name":"a","boundingType":{"name":"b","measurements":{"side":"1"}}},
+		{"name":"a","boundingType":{"name":"b","measurements":{"side":"1","top":"2"}}}
+	]}]`, defaultFacetPath)
+	require.Nil(t, err)
+	require.Equal(t, 1, facetGroups["a (b)"].Count)
+	require.Equal(t, 1, facetGroups["a (b)"].Facets["side"].Count)
+	require.Equal(t, map[string]int{"1": 1}, facetGroups["a (b)"].Facets["side"].Counts)
+	require.Equal(t, 1, facetGroups["a (b)"].Facets["top"].Count)
 }
 
 func TestBadPath(t *testing.T) {
@@ -431,3 +726,150 @@ var readmeExample = `
   }
 ]
 `
+
+// indexSnapshot copy the index in a form that doesn't depend on the order of the entries in each column.
+func indexSnapshot(facetEngine *FacetEngine) map[FacetKey][]string {
+	snapshot := map[FacetKey][]string{}
+	for key, column := range facetEngine.RecordLookup {
+		entries := []string{}
+		for i, ordinal := range column.Ordinals {
+			entries = append(entries, fmt.Sprintf("%d %s", ordinal, column.Values[i]))
+		}
+		sort.Strings(entries)
+		snapshot[key] = entries
+	}
+	return snapshot
+}
+
+func TestQueriesLeaveIndexUnchanged(t *testing.T) {
+	facetEngine, _, err := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	require.Nil(t, err)
+	before := indexSnapshot(facetEngine)
+	require.Equal(t, 3, len(before[FacetKey{Group: "total-area (hex-cylinder)", Facet: "diameter"}]))
+
+	_, facetGroups, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, 3, facetGroups["total-area (hex-cylinder)"].Count)
+	_, _ = facetEngine.AddFilter("total-area (hex-cylinder)", "diameter", Inclusive(16), Inclusive(16))
+	facetEngine.SetDisjunctive(true)
+	for i := 0; i < 3; i++ {
+		_, _, err = facetEngine.Query()
+		require.Nil(t, err)
+		_, err = facetEngine.Histogram("total-area (hex-cylinder)", "diameter", FixedWidth(1))
+		require.Nil(t, err)
+		_, err = facetEngine.Statistics("total-area (hex-cylinder)", "diameter")
+		require.Nil(t, err)
+	}
+	require.Equal(t, before, indexSnapshot(facetEngine))
+
+	facetEngine.ClearFilters()
+	_, facetGroups, err = facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, 3, facetGroups["total-area (hex-cylinder)"].Count)
+	require.Equal(t, map[string]int{"15": 1, "16": 2}, facetGroups["total-area (hex-cylinder)"].Facets["diameter"].Counts)
+}
+
+func TestConcurrentQueries(t *testing.T) {
+	facetEngine, _, err := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	require.Nil(t, err)
+	_, _ = facetEngine.AddFilter("total-area (hex-cylinder)", "diameter", Inclusive(16), Inclusive(16))
+	var wait sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			_, _, _ = facetEngine.Query()
+			_, _ = facetEngine.Histogram("total-area (hex-cylinder)", "diameter", FixedWidth(1))
+			_, _ = facetEngine.Statistics("total-area (hex-cylinder)", "diameter")
+		}()
+	}
+	wait.Wait()
+	ids, _, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"2", "3"}, ids)
+}
+
+// benchmarkObjects makes records shaped like the ones in the performance page, each with between 1 and 5 groupings of
+// between 1 and 3 metrics with values between 0 and 99.
+func benchmarkObjects(count int) []map[string]interface{} {
+	random := rand.New(rand.NewSource(1))
+	metrics := map[string][]string{
+		"cube":        {"side"},
+		"screwthread": {"height", "diameter", "pitch"},
+		"sphere":      {"diameter"},
+		"cuboid":      {"width", "height", "length"},
+		"cylinder":    {"diameter", "height"},
+	}
+	metricNames := []string{"cube", "screwthread", "sphere", "cuboid", "cylinder"}
+	objects := make([]map[string]interface{}, count)
+	for i := range objects {
+		measurements := []interface{}{}
+		for k := 0; k < random.Intn(5)+1; k++ {
+			metricName := metricNames[random.Intn(len(metricNames))]
+			values := map[string]interface{}{}
+			for _, name := range metrics[metricName] {
+				values[name] = strconv.Itoa(random.Intn(100))
+			}
+			measurements = append(measurements, map[string]interface{}{
+				"measurementName": fmt.Sprintf("a%d", random.Intn(10)),
+				"metrics":         map[string]interface{}{"metricName": metricName, "measurements": values},
+			})
+		}
+		objects[i] = map[string]interface{}{"id": fmt.Sprintf("record %d", i+1), "measurements": measurements}
+	}
+	return objects
+}
+
+func benchmarkEngine(b *testing.B, count int) *FacetEngine {
+	facetEngine := newFacetEngine()
+	_, err := facetEngine.InitializeObjects(benchmarkObjects(count), readmeFacetPath)
+	require.Nil(b, err)
+	return facetEngine
+}
+
+func BenchmarkFilter100k(b *testing.B) {
+	facetEngine := benchmarkEngine(b, 100000)
+	_, _ = facetEngine.AddFilter("a1 (cube)", "side", Inclusive(0), Exclusive(90))
+	_, _ = facetEngine.AddFilter("a2 (sphere)", "diameter", Inclusive(0), Exclusive(90))
+	_, _ = facetEngine.AddOrGroup(
+		NewFilter("a3 (cuboid)", "width", Inclusive(0), Exclusive(50)),
+		Not(NewFilter("a3 (cuboid)", "height", Inclusive(0), Exclusive(50))),
+	)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		facetEngine.evaluate(facetEngine.query.Root)
+	}
+}
+
+func BenchmarkQuery100k(b *testing.B) {
+	facetEngine := benchmarkEngine(b, 100000)
+	_, _ = facetEngine.AddFilter("a1 (cube)", "side", Inclusive(0), Exclusive(90))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := facetEngine.Query()
+		require.Nil(b, err)
+	}
+}
+
+func TestInitializeTwice(t *testing.T) {
+	facetEngine, facetGroups, err := NewFacetEngine(readmeExample, readmeFacetPath)
+	require.Nil(t, err)
+	counts := facetGroups["area (cube)"].Facets["side"].Counts
+	_, err = facetEngine.AddFilter("area (cube)", "side", Inclusive(8), Exclusive(12))
+	require.Nil(t, err)
+
+	facetGroups, err = facetEngine.Initialize(readmeExample, readmeFacetPath)
+	require.Nil(t, err)
+	require.Equal(t, counts, facetGroups["area (cube)"].Facets["side"].Counts)
+	require.Empty(t, facetEngine.ListFilters())
+	ids, _, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"record 1", "record 2"}, ids)
+
+	_, err = facetEngine.Initialize(`[{"id": "other", "measurements": []}, {"measurements": []}]`, readmeFacetPath)
+	require.Equal(t, ErrMissingID, ToError(err).Code)
+	ids, _, err = facetEngine.Query()
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"record 1", "record 2"}, ids)
+	require.Equal(t, 2, facetEngine.MemoryUsage().Records)
+}
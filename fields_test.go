@@ -0,0 +1,104 @@
+package facetengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var fieldsFacetPath = &FacetPath{
+	ArrayDotNotation:     "measurements",
+	NameFieldDotNotation: "measurementName",
+	NameMetaDotNotation:  "metrics.metricName",
+	ValueMapDotNotation:  "metrics.measurements",
+	Fields: []*FieldFacet{
+		{DotNotation: "price", Group: "Product"},
+		{DotNotation: "shipping.weight", Group: "Product", Facet: "Weight (kg)"},
+		{DotNotation: "tags", Group: "Product"},
+	},
+	CategoricalFacets: []string{"tags"},
+}
+
+var fieldsExample = `[
+	{"id": "1", "price": 10, "shipping": {"weight": "2.5"}, "tags": ["new", "sale"],
+		"measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "10"}}}]},
+	{"id": "2", "price": 25.5, "tags": "sale"},
+	{"id": "3", "price": null, "shipping": {"weight": 4}, "tags": [{"nested": "object"}]}
+]`
+
+func TestFieldFacets(t *testing.T) {
+	facetEngine, facetGroups, err := NewFacetEngine(fieldsExample, fieldsFacetPath)
+	require.Nil(t, err)
+	product := facetGroups["product"]
+	require.Equal(t, 3, product.Count)
+	require.Equal(t, map[string]int{"10": 1, "25.5": 1}, product.Facets["price"].Counts)
+	require.Equal(t, 2, product.Facets["weight (kg)"].Statistics.Count)
+	require.True(t, product.Facets["tags"].Categorical)
+	require.Equal(t, map[string]int{"new": 1, "sale": 2}, product.Facets["tags"].Counts)
+	require.Equal(t, 1, facetGroups["area (cube)"].Count)
+
+	_, err = facetEngine.AddFilter("product", "price", Inclusive(20), Inclusive(30))
+	require.Nil(t, err)
+	ids, _, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, []string{"2"}, ids)
+
+	facetEngine.ClearFilters()
+	_, err = facetEngine.AddExpression(And(Equals("product", "tags", "sale"), NewFilter("area (cube)", "side", Inclusive(0), Inclusive(10))))
+	require.Nil(t, err)
+	ids, _, err = facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, []string{"1"}, ids)
+
+	statistics, err := facetEngine.Statistics("product", "weight (kg)")
+	require.Nil(t, err)
+	require.Equal(t, 2.5, statistics.Max)
+}
+
+func TestFieldFacetErrors(t *testing.T) {
+	_, _, err := NewFacetEngine(`[{"id": "1", "price": "free"}]`, fieldsFacetPath)
+	require.Equal(t, ErrInvalidNumber, ToError(err).Code)
+	require.Equal(t, "price", ToError(err).Path)
+	require.Equal(t, "1", ToError(err).RecordID)
+
+	_, _, err = NewFacetEngine(`[{"id": "1", "scores": [1, "x"]}]`, &FacetPath{Fields: []*FieldFacet{{DotNotation: "scores", Group: "g"}}})
+	require.Equal(t, "scores.1", ToError(err).Path)
+
+	_, _, err = NewFacetEngine(`[]`, &FacetPath{Fields: []*FieldFacet{{DotNotation: "price"}}})
+	require.Equal(t, ErrInvalidArgument, ToError(err).Code)
+	require.Equal(t, "fields.0", ToError(err).Path)
+	_, _, err = NewFacetEngine("", &FacetPath{Fields: []*FieldFacet{nil}})
+	require.Equal(t, ErrInvalidArgument, ToError(err).Code)
+}
+
+func TestSnapshotFields(t *testing.T) {
+	facetEngine, _, err := NewFacetEngine(fieldsExample, fieldsFacetPath)
+	require.Nil(t, err)
+	restored := newFacetEngine()
+	_, err = restored.RestoreSnapshot(facetEngine.Snapshot())
+	require.Nil(t, err)
+	require.Equal(t, fieldsFacetPath, restored.facetPath)
+}
+
+func TestFieldFacetLargeNumbers(t *testing.T) {
+	facetPath := &FacetPath{Fields: []*FieldFacet{{DotNotation: "price", Group: "product"}}}
+	_, facetGroups, err := NewFacetEngine(`[{"id": "1", "price": 1234567}, {"id": "2", "price": [25000000, 0.5]}]`, facetPath)
+	require.Nil(t, err)
+	require.Equal(t, map[string]int{"1234567": 1, "25000000": 1, "0.5": 1}, facetGroups["product"].Facets["price"].Counts)
+	require.Equal(t, float64(25000000), facetGroups["product"].Facets["price"].Statistics.Max)
+}
+
+func TestFieldFacetDefaultName(t *testing.T) {
+	facetPath := &FacetPath{Fields: []*FieldFacet{
+		{DotNotation: `price\.usd`, Group: "product"},
+		{DotNotation: "sizes.*", Group: "product"},
+		{DotNotation: "parts.0.weight", Group: "part"},
+		{DotNotation: "$.dims[*]", Group: "part"},
+	}}
+	_, facetGroups, err := NewFacetEngine(`[{"id": "1", "price.usd": 5, "sizes": [1, 2], "parts": [{"weight": 3}], "dims": [4]}]`, facetPath)
+	require.Nil(t, err)
+	require.Equal(t, map[string]int{"5": 1}, facetGroups["product"].Facets["price.usd"].Counts)
+	require.Equal(t, map[string]int{"1": 1, "2": 1}, facetGroups["product"].Facets["sizes"].Counts)
+	require.Equal(t, map[string]int{"3": 1}, facetGroups["part"].Facets["weight"].Counts)
+	require.Equal(t, map[string]int{"4": 1}, facetGroups["part"].Facets["dims"].Counts)
+}
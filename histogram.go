@@ -0,0 +1,204 @@
+package facetengine
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+// Buckets describes how to split the values of a facet in to histogram buckets.
+type Buckets interface {
+	// Edges return the boundaries of the buckets for values between min and max.
+	Edges(min float64, max float64) []float64
+}
+
+// Bucket is a range of values and the number of records with a value in that range.  Min is inclusive and Max is
+// exclusive except for the last bucket where it is inclusive.
+type Bucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// maxBuckets is the most buckets a histogram can have, so a tiny width or a huge count can't exhaust memory.
+const maxBuckets = 10000
+
+// FixedWidth buckets are all width wide and aligned to multiples of width.
+func FixedWidth(width float64) Buckets {
+	return fixedWidth{width: width}
+}
+
+// FixedCount splits the values in to count equally wide buckets.
+func FixedCount(count int) Buckets {
+	return fixedCount{count: count}
+}
+
+// ExplicitEdges buckets are between each consecutive pair of edges.  Values outside the edges are not counted.
+func ExplicitEdges(edges ...float64) Buckets {
+	return explicitEdges{edges: edges}
+}
+
+type fixedWidth struct {
+	width float64
+}
+
+// Edges return nil when the values need more than maxBuckets buckets.
+func (b fixedWidth) Edges(min float64, max float64) []float64 {
+	start := math.Floor(min/b.width) * b.width
+	buckets := b.buckets(min, max)
+	if !(buckets <= maxBuckets) {
+		return nil
+	}
+	count := int(buckets)
+	edges := make([]float64, count+1)
+	for i := range edges {
+		edges[i] = start + float64(i)*b.width
+	}
+	return edges
+}
+
+// buckets return how many buckets the values between min and max need, +Inf or NaN when it can't be counted.
+func (b fixedWidth) buckets(min float64, max float64) float64 {
+	start := math.Floor(min/b.width) * b.width
+	return math.Floor((max-start)/b.width) + 1
+}
+
+type fixedCount struct {
+	count int
+}
+
+func (b fixedCount) Edges(min float64, max float64) []float64 {
+	if b.count <= 0 || b.count > maxBuckets {
+		return nil
+	}
+	if min == max {
+		return []float64{min, max}
+	}
+	edges := make([]float64, b.count+1)
+	for i := range edges {
+		edges[i] = min + float64(i)*(max-min)/float64(b.count)
+	}
+	edges[b.count] = max
+	return edges
+}
+
+type explicitEdges struct {
+	edges []float64
+}
+
+func (b explicitEdges) Edges(min float64, max float64) []float64 {
+	return b.edges
+}
+
+func validateBuckets(buckets Buckets) error {
+	switch b := buckets.(type) {
+	case nil:
+		return NewError(ErrInvalidArgument, "must specify buckets")
+	case fixedWidth:
+		if b.width <= 0 || math.IsInf(b.width, 0) || math.IsNaN(b.width) {
+			return NewError(ErrInvalidArgument, "bucket width must be greater than zero")
+		}
+	case fixedCount:
+		if b.count <= 0 {
+			return NewError(ErrInvalidArgument, "bucket count must be greater than zero")
+		}
+		if b.count > maxBuckets {
+			return NewError(ErrInvalidArgument, "bucket count must be at most %d", maxBuckets)
+		}
+	case explicitEdges:
+		if len(b.edges) < 2 {
+			return NewError(ErrInvalidArgument, "must specify at least two bucket edges")
+		}
+		if len(b.edges) > maxBuckets+1 {
+			return NewError(ErrInvalidArgument, "must specify at most %d bucket edges", maxBuckets+1)
+		}
+		if !sort.SliceIsSorted(b.edges, func(i, j int) bool { return b.edges[i] <= b.edges[j] }) {
+			return NewError(ErrInvalidArgument, "bucket edges must be in increasing order")
+		}
+	}
+	return nil
+}
+
+// bucketsJSON is the wire format of Buckets.  Exactly one of the fields is expected.
+type bucketsJSON struct {
+	Width float64   `json:"width,omitempty"`
+	Count int       `json:"count,omitempty"`
+	Edges []float64 `json:"edges,omitempty"`
+}
+
+// ParseBuckets reads {"width": 10}, {"count": 10} or {"edges": [0, 10, 50]}.
+func ParseBuckets(bucketsJSONString string) (Buckets, error) {
+	var raw bucketsJSON
+	err := json.Unmarshal([]byte(bucketsJSONString), &raw)
+	if err != nil {
+		return nil, ToError(err)
+	}
+	switch {
+	case raw.Width != 0:
+		return FixedWidth(raw.Width), nil
+	case raw.Count != 0:
+		return FixedCount(raw.Count), nil
+	case raw.Edges != nil:
+		return ExplicitEdges(raw.Edges...), nil
+	}
+	return nil, NewError(ErrInvalidArgument, "buckets must have one of width, count or edges")
+}
+
+// Histogram count the records matching the current filters in to buckets of the facet's values.  Values that are
+// infinite or NaN are not counted.
+func (f FacetEngine) Histogram(facetGroupName string, facetName string, buckets Buckets) ([]*Bucket, error) {
+	if err := validateBuckets(buckets); err != nil {
+		return nil, err
+	}
+	column, ok := f.RecordLookup[f.facetPath.key(facetGroupName, facetName)]
+	if !ok {
+		return nil, NewError(ErrUnknownFacet, "no facet named %s in %s", facetName, facetGroupName)
+	}
+	if column.Categorical {
+		return nil, NewError(ErrNotNumeric, "facet %s in %s is not numeric", facetName, facetGroupName)
+	}
+	matches := f.evaluate(f.query.Root)
+	ordinals := []uint32{}
+	values := []float64{}
+	for i, ordinal := range column.Ordinals {
+		if !matches.Contains(ordinal) || math.IsInf(column.Numbers[i], 0) || math.IsNaN(column.Numbers[i]) {
+			continue
+		}
+		ordinals = append(ordinals, ordinal)
+		values = append(values, column.Numbers[i])
+	}
+	var edges []float64
+	if _, explicit := buckets.(explicitEdges); explicit || len(values) > 0 {
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, value := range values {
+			min = math.Min(min, value)
+			max = math.Max(max, value)
+		}
+		if b, ok := buckets.(fixedWidth); ok && len(values) > 0 && !(b.buckets(min, max) <= maxBuckets) {
+			return nil, NewError(ErrInvalidArgument, "bucket width %v gives more than %d buckets", b.width, maxBuckets)
+		}
+		edges = buckets.Edges(min, max)
+	}
+	if len(edges) < 2 {
+		return []*Bucket{}, nil
+	}
+	results := make([]*Bucket, len(edges)-1)
+	counted := make([]*Bitmap, len(results))
+	for i := range results {
+		results[i] = &Bucket{Min: edges[i], Max: edges[i+1]}
+		counted[i] = NewBitmap()
+	}
+	last := len(edges) - 1
+	for i, value := range values {
+		bucket := sort.Search(len(edges), func(j int) bool { return edges[j] > value }) - 1
+		if bucket == last && value == edges[last] {
+			bucket--
+		}
+		if bucket < 0 || bucket >= last || counted[bucket].Contains(ordinals[i]) {
+			continue
+		}
+		counted[bucket].Add(ordinals[i])
+		results[bucket].Count++
+	}
+	return results, nil
+}
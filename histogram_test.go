@@ -0,0 +1,109 @@
+package facetengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramFixedWidth(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	buckets, err := facetEngine.Histogram("total-area (hex-cylinder)", "diameter", FixedWidth(10))
+	require.Nil(t, err)
+	require.Equal(t, []*Bucket{{Min: 10, Max: 20, Count: 3}}, buckets)
+	buckets, err = facetEngine.Histogram("total-area (hex-cylinder)", "diameter", FixedWidth(1))
+	require.Nil(t, err)
+	require.Equal(t, []*Bucket{{Min: 15, Max: 16, Count: 1}, {Min: 16, Max: 17, Count: 2}}, buckets)
+}
+
+func TestHistogramFixedCount(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	buckets, err := facetEngine.Histogram("total-area (hex-cylinder)", "diameter", FixedCount(2))
+	require.Nil(t, err)
+	require.Equal(t, []*Bucket{{Min: 15, Max: 15.5, Count: 1}, {Min: 15.5, Max: 16, Count: 2}}, buckets)
+	buckets, err = facetEngine.Histogram("total-area (hex-cylinder)", "height", FixedCount(4))
+	require.Nil(t, err)
+	require.Equal(t, []*Bucket{{Min: 20, Max: 20, Count: 3}}, buckets)
+}
+
+func TestHistogramExplicitEdges(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	buckets, err := facetEngine.Histogram("total-area (hex-cylinder)", "diameter", ExplicitEdges(0, 15, 16))
+	require.Nil(t, err)
+	require.Equal(t, []*Bucket{{Min: 0, Max: 15, Count: 0}, {Min: 15, Max: 16, Count: 3}}, buckets)
+	buckets, err = facetEngine.Histogram("total-area (hex-cylinder)", "diameter", ExplicitEdges(0, 10))
+	require.Nil(t, err)
+	require.Equal(t, []*Bucket{{Min: 0, Max: 10, Count: 0}}, buckets)
+}
+
+func TestHistogramFiltered(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	facetEngine.AddFilter("total-area (hex-cylinder)", "weird", Inclusive(1), Inclusive(1))
+	buckets, err := facetEngine.Histogram("total-area (hex-cylinder)", "diameter", FixedWidth(5))
+	require.Nil(t, err)
+	require.Equal(t, []*Bucket{{Min: 15, Max: 20, Count: 2}}, buckets)
+	facetEngine.AddFilter("total-area (hex-cylinder)", "weird", Inclusive(2), Inclusive(2))
+	buckets, err = facetEngine.Histogram("total-area (hex-cylinder)", "diameter", FixedWidth(5))
+	require.Nil(t, err)
+	require.Empty(t, buckets)
+}
+
+func TestHistogramErrors(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine(readmeExample, readmeFacetPath)
+	_, err := facetEngine.Histogram("area (cube)", "side", nil)
+	require.Error(t, err)
+	_, err = facetEngine.Histogram("area (cube)", "side", FixedWidth(0))
+	require.Error(t, err)
+	_, err = facetEngine.Histogram("area (cube)", "side", FixedCount(-1))
+	require.Error(t, err)
+	_, err = facetEngine.Histogram("area (cube)", "side", ExplicitEdges(1))
+	require.Error(t, err)
+	_, err = facetEngine.Histogram("area (cube)", "side", ExplicitEdges(2, 1))
+	require.Error(t, err)
+	_, err = facetEngine.Histogram("area (cube)", "missing", FixedWidth(1))
+	require.Error(t, err)
+}
+
+func TestParseBuckets(t *testing.T) {
+	buckets, err := ParseBuckets(`{"width":5}`)
+	require.Nil(t, err)
+	require.Equal(t, FixedWidth(5), buckets)
+	buckets, err = ParseBuckets(`{"count":3}`)
+	require.Nil(t, err)
+	require.Equal(t, FixedCount(3), buckets)
+	buckets, err = ParseBuckets(`{"edges":[0,1]}`)
+	require.Nil(t, err)
+	require.Equal(t, ExplicitEdges(0, 1), buckets)
+	_, err = ParseBuckets(`NOTJSON`)
+	require.Error(t, err)
+}
+
+func TestHistogramLimits(t *testing.T) {
+	facetPath := &FacetPath{Fields: []*FieldFacet{{DotNotation: "v", Group: "g"}}}
+	facetEngine, _, err := NewFacetEngine(`[{"id": "1", "v": "Inf"}, {"id": "2", "v": "-Inf"}, {"id": "3", "v": "NaN"}, {"id": "4", "v": 1}, {"id": "5", "v": 2}]`, facetPath)
+	require.Nil(t, err)
+	buckets, err := facetEngine.Histogram("g", "v", FixedWidth(1))
+	require.Nil(t, err)
+	require.Equal(t, []*Bucket{{Min: 1, Max: 2, Count: 1}, {Min: 2, Max: 3, Count: 1}}, buckets)
+	buckets, err = facetEngine.Histogram("g", "v", FixedCount(1))
+	require.Nil(t, err)
+	require.Equal(t, []*Bucket{{Min: 1, Max: 2, Count: 2}}, buckets)
+
+	_, err = facetEngine.Histogram("g", "v", FixedWidth(1e-300))
+	require.Equal(t, ErrInvalidArgument, ToError(err).Code)
+	_, err = facetEngine.Histogram("g", "v", FixedCount(1e9))
+	require.Equal(t, ErrInvalidArgument, ToError(err).Code)
+	buckets, err = facetEngine.Histogram("g", "v", FixedCount(maxBuckets))
+	require.Nil(t, err)
+	require.Equal(t, maxBuckets, len(buckets))
+	require.Nil(t, FixedWidth(1e-300).Edges(0, 1))
+	require.Nil(t, FixedCount(1e9).Edges(0, 1))
+
+	facetEngine, _, err = NewFacetEngine(`[{"id": "1", "v": -1e300}, {"id": "2", "v": 1e300}]`, facetPath)
+	require.Nil(t, err)
+	_, err = facetEngine.Histogram("g", "v", FixedWidth(1))
+	require.Equal(t, ErrInvalidArgument, ToError(err).Code)
+	buckets, err = facetEngine.Histogram("g", "v", FixedCount(2))
+	require.Nil(t, err)
+	require.Equal(t, 2, len(buckets))
+}
@@ -0,0 +1,530 @@
+package facetengine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a compiled JSONPath expression, see parseJSONPath for the subset that is supported.
+type jsonPath struct {
+	steps []pathStep
+}
+
+// pathStep takes each value found so far to the values after one segment of the expression.
+type pathStep interface {
+	apply(value interface{}, results []interface{}) []interface{}
+}
+
+type childStep string
+
+type indexStep int
+
+type wildcardStep struct{}
+
+type filterStep struct {
+	predicate predicate
+}
+
+// predicate is the expression in a [?( )] filter, it is evaluated with @ as each element.
+type predicate interface {
+	test(current interface{}) bool
+}
+
+type orPredicate []predicate
+
+type andPredicate []predicate
+
+type notPredicate struct {
+	predicate predicate
+}
+
+// comparison compares two operands, any value an operand finds can satisfy it.  With no operator it tests that the
+// left operand's path finds something, even null or false, or that its literal isn't null or false.
+type comparison struct {
+	left     operand
+	operator string
+	right    operand
+}
+
+// operand is a literal or a path relative to @.
+type operand struct {
+	literal interface{}
+	path    *jsonPath
+}
+
+// parseJSONPath compile an expression in this subset of JSONPath:
+//
+//	$                    the value the expression is applied to
+//	.name or ['name']    a key of an object, the bracket form can hold any character, e.g. ['a.b']
+//	[0] or .0            an element of an array, negative indexes count from the end
+//	[*] or .*            every element of an array or value of an object
+//	[?(expression)]      the elements of an array, or values of an object, that the expression is true for
+//
+// Filter expressions compare paths starting at @, the element being tested, with each other or with 'strings', numbers,
+// true, false and null using ==, !=, <, <=, > and >=.  Expressions combine with &&, || and ! and group with brackets,
+// nested at most maxFilterDepth deep.  A path on its own is true when the key exists, whatever its value, so
+// [?(@.active)] keeps {"active": false}; use @.active == true to test the value.  A string and a number are compared as
+// numbers when the string is one.  Recursive descent, slices, unions and functions are not supported.
+func parseJSONPath(expression string) (*jsonPath, error) {
+	p := &pathParser{input: expression}
+	p.skipSpace()
+	if !p.consume("$") {
+		return nil, p.fail("must start with $")
+	}
+	path, err := p.steps()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.input) {
+		return nil, p.fail("unexpected %q", p.input[p.pos:])
+	}
+	return path, nil
+}
+
+// selectValues apply the expression to value and return everything it finds.
+func (j *jsonPath) selectValues(value interface{}) []interface{} {
+	values := []interface{}{value}
+	for _, step := range j.steps {
+		var next []interface{}
+		for _, v := range values {
+			next = step.apply(v, next)
+		}
+		values = next
+	}
+	return values
+}
+
+func (c childStep) apply(value interface{}, results []interface{}) []interface{} {
+	if object, ok := value.(map[string]interface{}); ok {
+		if child, ok := object[string(c)]; ok {
+			return append(results, child)
+		}
+	}
+	return results
+}
+
+func (i indexStep) apply(value interface{}, results []interface{}) []interface{} {
+	array, ok := value.([]interface{})
+	if !ok {
+		return results
+	}
+	index := int(i)
+	if index < 0 {
+		index += len(array)
+	}
+	if index < 0 || index >= len(array) {
+		return results
+	}
+	return append(results, array[index])
+}
+
+func (wildcardStep) apply(value interface{}, results []interface{}) []interface{} {
+	return append(results, children(value)...)
+}
+
+func (f filterStep) apply(value interface{}, results []interface{}) []interface{} {
+	for _, child := range children(value) {
+		if f.predicate.test(child) {
+			results = append(results, child)
+		}
+	}
+	return results
+}
+
+// children return the elements of an array or the values of an object in key order.
+func children(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		results := make([]interface{}, len(keys))
+		for i, key := range keys {
+			results[i] = v[key]
+		}
+		return results
+	}
+	return nil
+}
+
+func (o orPredicate) test(current interface{}) bool {
+	for _, p := range o {
+		if p.test(current) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a andPredicate) test(current interface{}) bool {
+	for _, p := range a {
+		if !p.test(current) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n notPredicate) test(current interface{}) bool {
+	return !n.predicate.test(current)
+}
+
+func (c *comparison) test(current interface{}) bool {
+	if c.operator == "" {
+		if c.left.path != nil {
+			return len(c.left.path.selectValues(current)) > 0
+		}
+		return c.left.literal != nil && c.left.literal != false
+	}
+	lefts := c.left.values(current)
+	rights := c.right.values(current)
+	for _, left := range lefts {
+		for _, right := range rights {
+			if compare(left, c.operator, right) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (o operand) values(current interface{}) []interface{} {
+	if o.path != nil {
+		return o.path.selectValues(current)
+	}
+	return []interface{}{o.literal}
+}
+
+// compare two json values, numbers and strings holding numbers compare as numbers.
+func compare(left interface{}, operator string, right interface{}) bool {
+	if l, ok := toNumber(left); ok {
+		if r, ok := toNumber(right); ok {
+			switch operator {
+			case "==":
+				return l == r
+			case "!=":
+				return l != r
+			case "<":
+				return l < r
+			case "<=":
+				return l <= r
+			case ">":
+				return l > r
+			case ">=":
+				return l >= r
+			}
+		}
+	}
+	l, lok := left.(string)
+	r, rok := right.(string)
+	if lok && rok {
+		switch operator {
+		case "<":
+			return l < r
+		case "<=":
+			return l <= r
+		case ">":
+			return l > r
+		case ">=":
+			return l >= r
+		}
+	}
+	switch operator {
+	case "==":
+		return isScalar(left) && isScalar(right) && left == right
+	case "!=":
+		return !(isScalar(left) && isScalar(right) && left == right)
+	}
+	return false
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case nil, string, float64, bool:
+		return true
+	}
+	return false
+}
+
+// maxFilterDepth is how deeply brackets, negations and filters can nest, so an expression can't exhaust the stack.
+const maxFilterDepth = 32
+
+// pathParser reads an expression a character at a time.  depth is how many brackets, negations and filters enclose the
+// current position.
+type pathParser struct {
+	input string
+	pos   int
+	depth int
+}
+
+// enter one level deeper, failing when that is deeper than maxFilterDepth.  Call leave once the level is read.
+func (p *pathParser) enter() error {
+	p.depth++
+	if p.depth > maxFilterDepth {
+		return p.fail("nested more than %d deep", maxFilterDepth)
+	}
+	return nil
+}
+
+func (p *pathParser) leave() {
+	p.depth--
+}
+
+func (p *pathParser) fail(format string, args ...interface{}) error {
+	return NewError(ErrInvalidArgument, "invalid path %s: %s at %d", p.input, fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *pathParser) skipSpace() {
+	for p.pos < len(p.input) && p.input[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *pathParser) peek(token string) bool {
+	return strings.HasPrefix(p.input[p.pos:], token)
+}
+
+func (p *pathParser) consume(token string) bool {
+	if p.peek(token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+// steps read segments until one can't start.
+func (p *pathParser) steps() (*jsonPath, error) {
+	path := &jsonPath{}
+	for {
+		switch {
+		case p.peek(".."):
+			return nil, p.fail("recursive descent is not supported")
+		case p.consume("."):
+			if p.consume("*") {
+				path.steps = append(path.steps, wildcardStep{})
+				continue
+			}
+			name := p.name()
+			if name == "" {
+				return nil, p.fail("expected a name after .")
+			}
+			if index, err := strconv.Atoi(name); err == nil {
+				path.steps = append(path.steps, indexStep(index))
+			} else {
+				path.steps = append(path.steps, childStep(name))
+			}
+		case p.consume("["):
+			step, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			path.steps = append(path.steps, step)
+		default:
+			return path, nil
+		}
+	}
+}
+
+// name read an unquoted key, which ends at anything that can follow it.
+func (p *pathParser) name() string {
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune(".[]() =!<>&|", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// bracket read what follows a [ up to and including the ].
+func (p *pathParser) bracket() (pathStep, error) {
+	p.skipSpace()
+	var step pathStep
+	switch {
+	case p.consume("*"):
+		step = wildcardStep{}
+	case p.consume("?"):
+		p.skipSpace()
+		predicate, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		step = filterStep{predicate: predicate}
+	case p.peek("'") || p.peek(`"`):
+		name, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		step = childStep(name)
+	default:
+		start := p.pos
+		for p.pos < len(p.input) && (p.input[p.pos] == '-' || (p.input[p.pos] >= '0' && p.input[p.pos] <= '9')) {
+			p.pos++
+		}
+		index, err := strconv.Atoi(p.input[start:p.pos])
+		if err != nil {
+			p.pos = start
+			return nil, p.fail("expected an index, *, a quoted name or ?")
+		}
+		step = indexStep(index)
+	}
+	p.skipSpace()
+	if !p.consume("]") {
+		return nil, p.fail("expected ]")
+	}
+	return step, nil
+}
+
+// quoted read a string in single or double quotes, a backslash escapes the next character.
+func (p *pathParser) quoted() (string, error) {
+	quote := p.input[p.pos]
+	p.pos++
+	var value strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		switch {
+		case c == '\\' && p.pos < len(p.input):
+			value.WriteByte(p.input[p.pos])
+			p.pos++
+		case c == quote:
+			return value.String(), nil
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", p.fail("unterminated string")
+}
+
+func (p *pathParser) or() (predicate, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	predicates := orPredicate{}
+	for {
+		and, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, and)
+		p.skipSpace()
+		if !p.consume("||") {
+			break
+		}
+	}
+	if len(predicates) == 1 {
+		return predicates[0], nil
+	}
+	return predicates, nil
+}
+
+func (p *pathParser) and() (predicate, error) {
+	predicates := andPredicate{}
+	for {
+		unary, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, unary)
+		p.skipSpace()
+		if !p.consume("&&") {
+			break
+		}
+	}
+	if len(predicates) == 1 {
+		return predicates[0], nil
+	}
+	return predicates, nil
+}
+
+// unary read a negation, a bracketed expression or a comparison.
+func (p *pathParser) unary() (predicate, error) {
+	p.skipSpace()
+	if p.peek("!") && !p.peek("!=") {
+		p.pos++
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		predicate, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notPredicate{predicate: predicate}, nil
+	}
+	if p.consume("(") {
+		predicate, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(")") {
+			return nil, p.fail("expected )")
+		}
+		return predicate, nil
+	}
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	for _, operator := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(operator) {
+			p.skipSpace()
+			right, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return &comparison{left: left, operator: operator, right: right}, nil
+		}
+	}
+	return &comparison{left: left}, nil
+}
+
+// operand read a path from @ or a literal.
+func (p *pathParser) operand() (operand, error) {
+	switch {
+	case p.consume("@"):
+		path, err := p.steps()
+		if err != nil {
+			return operand{}, err
+		}
+		return operand{path: path}, nil
+	case p.peek("'") || p.peek(`"`):
+		value, err := p.quoted()
+		return operand{literal: value}, err
+	case p.consume("true"):
+		return operand{literal: true}, nil
+	case p.consume("false"):
+		return operand{literal: false}, nil
+	case p.consume("null"):
+		return operand{literal: nil}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.input) && strings.ContainsRune("+-.0123456789eE", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	number, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		p.pos = start
+		return operand{}, p.fail("expected @, a string, a number, true, false or null")
+	}
+	return operand{literal: number}, nil
+}
@@ -0,0 +1,136 @@
+package facetengine
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPathSelect(t *testing.T) {
+	var data map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(`{
+		"measurements": [
+			{"type": "outer", "size": 10, "tags": ["a", "b"], "ok": true},
+			{"type": "inner", "size": "20", "ok": false},
+			{"type": "outer", "size": 30, "unit": null},
+			"not an object"
+		],
+		"a.b": {"c": 1},
+		"dims": {"x": 1, "y": 2}
+	}`), &data))
+	tests := map[string][]interface{}{
+		"$":                        {data},
+		"$.measurements[0].type":   {"outer"},
+		"$.measurements.0.type":    {"outer"},
+		"$.measurements[-1]":       {"not an object"},
+		"$.measurements[9]":        {},
+		"$['a.b'].c":               {1.0},
+		`$["a.b"]['c']`:            {1.0},
+		"$.measurements[*].size":   {10.0, "20", 30.0},
+		"$.measurements.*.tags[*]": {"a", "b"},
+		"$.dims.*":                 {1.0, 2.0},
+		"$.dims[?(@ > 1)]":         {2.0},
+		"$.measurements[3].type":   {},
+		"$.measurements[2].unit":   {nil},
+		"$.measurements[?(@.type == 'outer')].size":                     {10.0, 30.0},
+		"$.measurements[?(@.type != 'outer')].size":                     {"20"},
+		`$.measurements[?(@.type == "outer" && @.size > 15)].size`:      {30.0},
+		"$.measurements[?(@.type == 'inner' || @.size < 15)].size":      {10.0, "20"},
+		"$.measurements[?(@.size >= 20)].size":                          {"20", 30.0},
+		"$.measurements[?(@.size == 20)].type":                          {"inner"},
+		"$.measurements[?(@.tags)].size":                                {10.0},
+		"$.measurements[?(!@.tags)].size":                               {"20", 30.0},
+		"$.measurements[?(@.ok == true)].size":                          {10.0},
+		"$.measurements[?(@.ok)].size":                                  {10.0, "20"},
+		"$.measurements[?(@.ok == false)].size":                         {"20"},
+		"$.measurements[?(@.unit)].size":                                {30.0},
+		"$.measurements[?(!@.unit)].size":                               {10.0, "20"},
+		"$.measurements[?(true)].size":                                  {10.0, "20", 30.0},
+		"$.measurements[?(false || null)].size":                         {},
+		"$.measurements[?(@.unit == null)].size":                        {30.0},
+		"$.measurements[?(@.tags[*] == 'b')].type":                      {"outer"},
+		"$.measurements[?(@.type > 'j')].type":                          {"outer", "outer"},
+		"$.measurements[?(!(@.type == 'outer' || @.size == '20'))]":     {"not an object"},
+		"$.measurements[?((@.type == 'inner' || @.ok) && @.size < 15)]": {data["measurements"].([]interface{})[0]},
+	}
+	for expression, expected := range tests {
+		path, err := parseJSONPath(expression)
+		require.Nil(t, err, expression)
+		found := path.selectValues(data)
+		if len(expected) == 0 {
+			require.Empty(t, found, expression)
+		} else {
+			require.Equal(t, expected, found, expression)
+		}
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"measurements",
+		"$.",
+		"$..type",
+		"$[",
+		"$[x]",
+		"$['unterminated]",
+		"$[?(@.type == )]",
+		"$[?(@.type == 'outer']",
+		"$[?((@.type == 'outer')]",
+		"$.measurements extra",
+		"$[?(" + strings.Repeat("(", maxFilterDepth) + "@.a" + strings.Repeat(")", maxFilterDepth) + ")]",
+		"$[?(" + strings.Repeat("!", maxFilterDepth) + "@.a)]",
+		"$" + strings.Repeat("[?(@", maxFilterDepth) + strings.Repeat(")]", maxFilterDepth),
+		"$[?(" + strings.Repeat("(", 1000000) + "@.a)]",
+	} {
+		_, err := parseJSONPath(expression)
+		require.NotNil(t, err, expression)
+		require.Equal(t, ErrInvalidArgument, ToError(err).Code, expression)
+	}
+	_, err := parseJSONPath("$[?(" + strings.Repeat("(", maxFilterDepth-2) + "@.a" + strings.Repeat(")", maxFilterDepth-2) + ")]")
+	require.Nil(t, err)
+}
+
+func TestJSONPathFacetPath(t *testing.T) {
+	records := `[
+		{"meta": {"id": "record 1"}, "bounds": [
+			{"type": "outer", "name": "box", "kind": "cube", "measurements": {"side": 10}},
+			{"type": "inner", "name": "box", "kind": "cube", "measurements": {"side": 8}}
+		], "unit price": 5, "tags": ["a", "b"]},
+		{"meta": {"id": "record 2"}, "bounds": [
+			{"type": "outer", "name": "box", "kind": "sphere", "measurements": {"diameter": 4}}
+		], "unit price": 7}
+	]`
+	facetPath := &FacetPath{
+		IDDotNotation:        "$.meta.id",
+		ArrayDotNotation:     "$.bounds[?(@.type == 'outer')]",
+		NameFieldDotNotation: "$.name",
+		NameMetaDotNotation:  "kind",
+		ValueMapDotNotation:  "$.measurements",
+		Fields: []*FieldFacet{
+			{DotNotation: "$['unit price']", Group: "product"},
+			{DotNotation: "$.tags[*]", Group: "product"},
+		},
+		CategoricalFacets: []string{"tags"},
+	}
+	_, facetGroups, err := NewFacetEngine(records, facetPath)
+	require.Nil(t, err)
+	require.Equal(t, map[string]int{"10": 1}, facetGroups["box (cube)"].Facets["side"].Counts)
+	require.Equal(t, 1, facetGroups["box (sphere)"].Count)
+	require.Equal(t, map[string]int{"5": 1, "7": 1}, facetGroups["product"].Facets["unit price"].Counts)
+	require.Equal(t, map[string]int{"a": 1, "b": 1}, facetGroups["product"].Facets["tags"].Counts)
+
+	_, _, err = NewFacetEngine(`[{"meta": {"id": "record 1"}, "bounds": [{"type": "outer", "name": "box", "kind": "cube", "measurements": {"side": "x"}}]}]`, facetPath)
+	require.Equal(t, ErrInvalidNumber, ToError(err).Code)
+	require.Equal(t, "$.bounds[?(@.type == 'outer')][0].measurements.side", ToError(err).Path)
+	_, _, err = NewFacetEngine(`[{"meta": {"id": "record 1"}, "unit price": [1, "x"]}]`, facetPath)
+	require.Equal(t, "$['unit price'][1]", ToError(err).Path)
+
+	facetPath.Rules = []*ExtractionRule{{ArrayDotNotation: "$.bounds[?(@.type == )]"}}
+	_, _, err = NewFacetEngine(records, facetPath)
+	require.Equal(t, ErrInvalidArgument, ToError(err).Code)
+	require.Equal(t, "rules.0.arrayDotNotation", ToError(err).Path)
+	require.Contains(t, err.Error(), "@.type == ")
+}
@@ -0,0 +1,181 @@
+package facetengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var labelsExample = `[
+	{"id": "1", "measurements": [{"measurementName": "Area", "metrics": {"metricName": "Cube", "measurements": {"Side": "10"}}}]},
+	{"id": "2", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "20"}}}]}
+]`
+
+func TestLabelsAndKeys(t *testing.T) {
+	facetEngine, facetGroups, err := NewFacetEngine(labelsExample, readmeFacetPath)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(facetGroups))
+	require.Equal(t, 2, facetGroups["area (cube)"].Count)
+	require.Equal(t, "Area (Cube)", facetGroups["area (cube)"].Name)
+	require.Equal(t, "Side", facetGroups["area (cube)"].Facets["side"].Name)
+
+	// filters, histograms and statistics take the label or the key.
+	_, err = facetEngine.AddFilter("Area (Cube)", "Side", Inclusive(15), Inclusive(25))
+	require.Nil(t, err)
+	ids, _, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, []string{"2"}, ids)
+	facetEngine.ClearFilters()
+	statistics, err := facetEngine.Statistics("AREA (CUBE)", "side")
+	require.Nil(t, err)
+	require.Equal(t, 2, statistics.Count)
+	buckets, err := facetEngine.Histogram("area (cube)", "SIDE", FixedCount(1))
+	require.Nil(t, err)
+	require.Equal(t, 2, buckets[0].Count)
+
+	restored := newFacetEngine()
+	restoredGroups, err := restored.RestoreSnapshot(facetEngine.Snapshot())
+	require.Nil(t, err)
+	require.Equal(t, "Area (Cube)", restoredGroups["area (cube)"].Name)
+	require.Equal(t, "Side", restoredGroups["area (cube)"].Facets["side"].Name)
+}
+
+func TestCaseFoldingNone(t *testing.T) {
+	facetPath := *readmeFacetPath
+	facetPath.CaseFolding = CaseFoldingNone
+	facetPath.CategoricalFacets = []string{"Side"}
+	facetEngine, facetGroups, err := NewFacetEngine(labelsExample, &facetPath)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(facetGroups))
+	require.Equal(t, "Area (Cube)", facetGroups["Area (Cube)"].Name)
+	require.True(t, facetGroups["Area (Cube)"].Facets["Side"].Categorical)
+	require.False(t, facetGroups["area (cube)"].Facets["side"].Categorical)
+
+	_, err = facetEngine.AddFilter("area (cube)", "side", Inclusive(0), Inclusive(100))
+	require.Nil(t, err)
+	ids, _, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, []string{"2"}, ids)
+
+	restored := newFacetEngine()
+	_, err = restored.RestoreSnapshot(facetEngine.Snapshot())
+	require.Nil(t, err)
+	require.Equal(t, CaseFoldingNone, restored.facetPath.CaseFolding)
+
+	facetPath.CaseFolding = "upper"
+	_, _, err = NewFacetEngine(labelsExample, &facetPath)
+	require.Equal(t, ErrInvalidArgument, ToError(err).Code)
+	require.Equal(t, "caseFolding", ToError(err).Path)
+}
+
+func TestKeysDoNotCollide(t *testing.T) {
+	facetPath := &FacetPath{Fields: []*FieldFacet{
+		{DotNotation: "x", Group: "a - b", Facet: "c"},
+		{DotNotation: "y", Group: "a", Facet: "b - c"},
+	}}
+	facetEngine, facetGroups, err := NewFacetEngine(`[{"id": "1", "x": 1}, {"id": "2", "y": 2}]`, facetPath)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(facetEngine.RecordLookup))
+	require.Equal(t, map[string]int{"1": 1}, facetGroups["a - b"].Facets["c"].Counts)
+	require.Equal(t, map[string]int{"2": 1}, facetGroups["a"].Facets["b - c"].Counts)
+
+	_, err = facetEngine.AddFilter("a", "b - c", Inclusive(0), Inclusive(10))
+	require.Nil(t, err)
+	ids, _, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, []string{"2"}, ids)
+}
+
+func TestFacetPathGroupName(t *testing.T) {
+	facetPath := &FacetPath{
+		ArrayDotNotation:     "measurements",
+		NameFieldDotNotation: "measurementName",
+		NameMetaDotNotation:  "metrics.metricName",
+		ValueMapDotNotation:  "metrics.measurements",
+		GroupName:            "{meta}: {name}",
+		Rules: []*ExtractionRule{
+			{ArrayDotNotation: "bounds", NameFieldDotNotation: "name", NameMetaDotNotation: "kind", ValueMapDotNotation: "values"},
+			{ArrayDotNotation: "labels", NameMetaDotNotation: "kind", ValueMapDotNotation: "values", GroupName: "label {meta}"},
+		},
+	}
+	_, facetGroups, err := NewFacetEngine(`[{"id": "1",
+		"measurements": [{"measurementName": "Area", "metrics": {"metricName": "Cube", "measurements": {"side": "10"}}}],
+		"bounds": [{"name": "Outer", "kind": "Box", "values": {"width": "4"}}],
+		"labels": [{"kind": "Size", "values": {"weight": "2"}}]
+	}]`, facetPath)
+	require.Nil(t, err)
+	require.Equal(t, "Cube: Area", facetGroups["cube: area"].Name)
+	require.Equal(t, "Box: Outer", facetGroups["box: outer"].Name)
+	require.Equal(t, "label Size", facetGroups["label size"].Name)
+	require.Equal(t, "", facetPath.Rules[0].GroupName)
+}
+
+func TestGroupLabelSharedByColumns(t *testing.T) {
+	facetEngine, facetGroups, err := NewFacetEngine(`[
+		{"id": "1", "measurements": [{"measurementName": "Area", "metrics": {"metricName": "Cube", "measurements": {"side": "10"}}}]},
+		{"id": "2", "measurements": [{"measurementName": "AREA", "metrics": {"metricName": "cube", "measurements": {"depth": "5"}}}]}
+	]`, readmeFacetPath)
+	require.Nil(t, err)
+	require.Equal(t, "Area (Cube)", facetGroups["area (cube)"].Name)
+	for _, column := range facetEngine.RecordLookup {
+		require.Equal(t, "Area (Cube)", column.GroupLabel)
+	}
+
+	require.Nil(t, facetEngine.AddRecords(`[{"id": "3", "measurements": [{"measurementName": "area", "metrics": {"metricName": "CUBE", "measurements": {"width": "2"}}}]}]`))
+	restored := newFacetEngine()
+	_, err = restored.RestoreSnapshot(facetEngine.Snapshot())
+	require.Nil(t, err)
+	require.Nil(t, restored.AddRecords(`[{"id": "4", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"height": "1"}}}]}]`))
+	for _, column := range restored.RecordLookup {
+		require.Equal(t, "Area (Cube)", column.GroupLabel)
+	}
+	require.Equal(t, 4, len(restored.RecordLookup))
+}
+
+func TestCategoricalFacetKeys(t *testing.T) {
+	facetPath := &FacetPath{
+		Fields: []*FieldFacet{
+			{DotNotation: "product.size", Group: "Product", Facet: "Size"},
+			{DotNotation: "box.size", Group: "box", Facet: "size"},
+		},
+		CategoricalFacetKeys: []FacetKey{{Group: "Product", Facet: "SIZE"}},
+	}
+	facetEngine, facetGroups, err := NewFacetEngine(`[{"id": "1", "product": {"size": "large"}, "box": {"size": 10}}]`, facetPath)
+	require.Nil(t, err)
+	require.True(t, facetGroups["product"].Facets["size"].Categorical)
+	require.False(t, facetGroups["box"].Facets["size"].Categorical)
+
+	restored := newFacetEngine()
+	_, err = restored.RestoreSnapshot(facetEngine.Snapshot())
+	require.Nil(t, err)
+	require.Equal(t, facetPath.CategoricalFacetKeys, restored.facetPath.CategoricalFacetKeys)
+
+	// a facet named without its group is categorical in every group.
+	facetPath.CategoricalFacetKeys = nil
+	facetPath.CategoricalFacets = []string{"size"}
+	_, facetGroups, err = NewFacetEngine(`[{"id": "1", "product": {"size": "large"}, "box": {"size": "small"}}]`, facetPath)
+	require.Nil(t, err)
+	require.True(t, facetGroups["box"].Facets["size"].Categorical)
+
+	facetPath.CategoricalFacetKeys = []FacetKey{{Group: "product"}}
+	_, _, err = NewFacetEngine(`[]`, facetPath)
+	require.Equal(t, ErrInvalidArgument, ToError(err).Code)
+	require.Equal(t, "categoricalFacetKeys.0", ToError(err).Path)
+}
+
+func TestKeysDifferingByCaseIndexedOnce(t *testing.T) {
+	facetEngine, facetGroups, err := NewFacetEngine(`[
+		{"id": "1", "measurements": [{"measurementName": "Area", "metrics": {"metricName": "Cube", "measurements": {"Side": "1", "side": "1"}}}]}
+	]`, readmeFacetPath)
+	require.Nil(t, err)
+	facet := facetGroups["area (cube)"].Facets["side"]
+	require.Equal(t, map[string]int{"1": 1}, facet.Counts)
+	require.Equal(t, 1, facet.Statistics.Count)
+	statistics, err := facetEngine.Statistics("area (cube)", "side")
+	require.Nil(t, err)
+	require.Equal(t, 1, statistics.Count)
+	buckets, err := facetEngine.Histogram("area (cube)", "side", FixedCount(1))
+	require.Nil(t, err)
+	require.Equal(t, 1, buckets[0].Count)
+	require.Equal(t, 1, facetEngine.RecordLookup[FacetKey{Group: "area (cube)", Facet: "side"}].Len())
+}
@@ -0,0 +1,89 @@
+package facetengine
+
+import "runtime"
+
+// Sizes used to estimate memory, for a 64 bit platform.  wasm has 32 bit pointers so the estimates there are high.
+const (
+	stringHeaderBytes = 16
+	sliceHeaderBytes  = 24
+	interfaceBytes    = 16
+	mapEntryBytes     = 48
+	chunkBytes        = 8 + sliceHeaderBytes + 2
+)
+
+// MemoryUsage estimate of the memory held by the engine in bytes.  IDs is the record ids and the bitmaps of which are
+// loaded, Index is the columns the facets are computed from, and Records is the objects kept when
+// FacetPath.RetainRecords is set.  Heap is the size of the whole go heap when the report was made, including garbage
+// not yet collected.
+type MemoryUsage struct {
+	Records       int  `json:"records"`
+	Columns       int  `json:"columns"`
+	Entries       int  `json:"entries"`
+	IDBytes       int  `json:"idBytes"`
+	IndexBytes    int  `json:"indexBytes"`
+	RecordsBytes  int  `json:"recordsBytes"`
+	TotalBytes    int  `json:"totalBytes"`
+	HeapBytes     int  `json:"heapBytes"`
+	RetainRecords bool `json:"retainRecords"`
+}
+
+// MemoryUsage report how much memory the ids, the index and any retained records take.
+func (f *FacetEngine) MemoryUsage() *MemoryUsage {
+	usage := &MemoryUsage{
+		Records:       f.allIds.Len(),
+		Columns:       len(f.RecordLookup),
+		RetainRecords: f.facetPath != nil && f.facetPath.RetainRecords,
+	}
+	for _, id := range f.dictionary.values {
+		usage.IDBytes += stringHeaderBytes + len(id) + mapEntryBytes
+	}
+	usage.IDBytes += bitmapBytes(f.allIds.bitmap) + bitmapBytes(f.ids.bitmap)
+	for _, column := range f.RecordLookup {
+		usage.Entries += column.Len()
+		usage.IndexBytes += mapEntryBytes + 2*stringHeaderBytes + len(column.Group) + len(column.Facet)
+		usage.IndexBytes += len(column.GroupLabel) + len(column.FacetLabel)
+		usage.IndexBytes += 3*sliceHeaderBytes + cap(column.Values)*stringHeaderBytes + cap(column.Numbers)*8 + cap(column.Ordinals)*4
+		for _, value := range column.Values {
+			usage.IndexBytes += len(value)
+		}
+	}
+	for _, genericObject := range f.records {
+		usage.RecordsBytes += mapEntryBytes + objectBytes(genericObject)
+	}
+	usage.TotalBytes = usage.IDBytes + usage.IndexBytes + usage.RecordsBytes
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	usage.HeapBytes = int(stats.HeapAlloc)
+	return usage
+}
+
+func bitmapBytes(b *Bitmap) int {
+	bytes := sliceHeaderBytes
+	for _, c := range b.chunks {
+		bytes += chunkBytes + cap(c.words)*8
+	}
+	return bytes
+}
+
+// objectBytes estimate the size of a value decoded by json.Unmarshal.
+func objectBytes(value interface{}) int {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		bytes := mapEntryBytes
+		for key, child := range v {
+			bytes += mapEntryBytes + len(key) + objectBytes(child)
+		}
+		return bytes
+	case []interface{}:
+		bytes := sliceHeaderBytes + cap(v)*interfaceBytes
+		for _, child := range v {
+			bytes += objectBytes(child)
+		}
+		return bytes
+	case string:
+		return stringHeaderBytes + len(v)
+	case float64:
+		return 8
+	}
+	return 0
+}
@@ -0,0 +1,68 @@
+package facetengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetainRecords(t *testing.T) {
+	facetEngine, _, err := NewFacetEngine(readmeExample, readmeFacetPath)
+	require.Nil(t, err)
+	_, err = facetEngine.Record("record 1")
+	require.Equal(t, ErrUnknownRecord, ToError(err).Code)
+	require.Contains(t, err.Error(), "retainRecords")
+	require.Empty(t, facetEngine.records)
+
+	retained := *readmeFacetPath
+	retained.RetainRecords = true
+	facetEngine, _, err = NewFacetEngine(readmeExample, &retained)
+	require.Nil(t, err)
+	record, err := facetEngine.Record("record 2")
+	require.Nil(t, err)
+	require.Equal(t, "record 2", record["id"])
+
+	require.Nil(t, facetEngine.UpdateRecord(`{"id": "record 2", "measurements": [], "updated": true}`))
+	record, err = facetEngine.Record("record 2")
+	require.Nil(t, err)
+	require.Equal(t, true, record["updated"])
+
+	require.Nil(t, facetEngine.DeleteRecords([]string{"record 2"}))
+	_, err = facetEngine.Record("record 2")
+	require.Equal(t, ErrUnknownRecord, ToError(err).Code)
+	require.Equal(t, 1, len(facetEngine.records))
+}
+
+func TestMemoryUsage(t *testing.T) {
+	objects := benchmarkObjects(1000)
+	facetEngine := newFacetEngine()
+	_, err := facetEngine.InitializeObjects(objects, readmeFacetPath)
+	require.Nil(t, err)
+	usage := facetEngine.MemoryUsage()
+	require.Equal(t, 1000, usage.Records)
+	require.False(t, usage.RetainRecords)
+	require.Equal(t, 0, usage.RecordsBytes)
+	require.True(t, usage.IDBytes > 1000*len("record 1000"))
+	require.True(t, usage.IndexBytes > usage.Entries*(stringHeaderBytes+8+4))
+	require.Equal(t, usage.IDBytes+usage.IndexBytes, usage.TotalBytes)
+	require.True(t, usage.HeapBytes > 0)
+
+	retained := *readmeFacetPath
+	retained.RetainRecords = true
+	retainedEngine := newFacetEngine()
+	_, err = retainedEngine.InitializeObjects(objects, &retained)
+	require.Nil(t, err)
+	retainedUsage := retainedEngine.MemoryUsage()
+	require.True(t, retainedUsage.RetainRecords)
+	require.Equal(t, usage.Entries, retainedUsage.Entries)
+	require.True(t, retainedUsage.RecordsBytes > usage.IndexBytes)
+	require.Equal(t, retainedUsage.IDBytes+retainedUsage.IndexBytes+retainedUsage.RecordsBytes, retainedUsage.TotalBytes)
+}
+
+func TestObjectBytes(t *testing.T) {
+	require.Equal(t, stringHeaderBytes+3, objectBytes("abc"))
+	require.Equal(t, 8, objectBytes(1.5))
+	require.Equal(t, 0, objectBytes(nil))
+	require.Equal(t, sliceHeaderBytes+interfaceBytes+8, objectBytes([]interface{}{1.5}))
+	require.Equal(t, 2*mapEntryBytes+1+8, objectBytes(map[string]interface{}{"a": 1.5}))
+}
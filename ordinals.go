@@ -0,0 +1,29 @@
+package facetengine
+
+import "fmt"
+
+// Ordinals return the ordinal of each of the ids.  Ordinals count up from zero in the order records are first loaded
+// and are kept when a record is deleted so that updating a record doesn't change its ordinal.
+func (f *FacetEngine) Ordinals(ids []string) ([]uint32, error) {
+	results := make([]uint32, len(ids))
+	for i, id := range ids {
+		ordinal, ok := f.dictionary.ordinals[id]
+		if !ok || !f.allIds.bitmap.Contains(ordinal) {
+			return nil, &Error{Code: ErrUnknownRecord, Message: fmt.Sprintf("no record with id %s", id), RecordID: id}
+		}
+		results[i] = ordinal
+	}
+	return results, nil
+}
+
+// IDs return the id of each of the ordinals.
+func (f *FacetEngine) IDs(ordinals []uint32) ([]string, error) {
+	results := make([]string, len(ordinals))
+	for i, ordinal := range ordinals {
+		if !f.allIds.bitmap.Contains(ordinal) {
+			return nil, NewError(ErrUnknownRecord, "no record with ordinal %d", ordinal)
+		}
+		results[i] = f.dictionary.values[ordinal]
+	}
+	return results, nil
+}
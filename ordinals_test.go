@@ -0,0 +1,40 @@
+package facetengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrdinals(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+"]", defaultFacetPath)
+	ordinals, err := facetEngine.Ordinals([]string{"2", "1"})
+	require.Nil(t, err)
+	require.Equal(t, []uint32{1, 0}, ordinals)
+	ids, err := facetEngine.IDs([]uint32{0, 1})
+	require.Nil(t, err)
+	require.Equal(t, []string{"1", "2"}, ids)
+
+	require.Nil(t, facetEngine.AddRecords("["+object3+"]"))
+	ordinals, _ = facetEngine.Ordinals([]string{"3"})
+	require.Equal(t, []uint32{2}, ordinals)
+
+	_, err = facetEngine.Ordinals([]string{"missing"})
+	require.Equal(t, ErrUnknownRecord, ToError(err).Code)
+	_, err = facetEngine.IDs([]uint32{3})
+	require.Equal(t, ErrUnknownRecord, ToError(err).Code)
+}
+
+func TestOrdinalsKeptOnUpdate(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+"]", defaultFacetPath)
+	require.Nil(t, facetEngine.UpdateRecord(object1))
+	ordinals, err := facetEngine.Ordinals([]string{"1"})
+	require.Nil(t, err)
+	require.Equal(t, []uint32{0}, ordinals)
+
+	require.Nil(t, facetEngine.DeleteRecords([]string{"1"}))
+	_, err = facetEngine.IDs([]uint32{0})
+	require.Error(t, err)
+	_, err = facetEngine.Ordinals([]string{"1"})
+	require.Error(t, err)
+}
@@ -0,0 +1,169 @@
+package facetengine
+
+import (
+	"strconv"
+	"strings"
+)
+
+// wildcard is the path segment that fans out over every element of an array.
+const wildcard = "*"
+
+// selector finds the values at a path in a record, nulls are never found.
+type selector interface {
+	find(value interface{}) []interface{}
+}
+
+// dotPath is a dot notation path split in to its segments.
+type dotPath []string
+
+func (d dotPath) find(value interface{}) []interface{} {
+	return walkPath(value, d)
+}
+
+func (j *jsonPath) find(value interface{}) []interface{} {
+	results := []interface{}{}
+	for _, v := range j.selectValues(value) {
+		if v != nil {
+			results = append(results, v)
+		}
+	}
+	return results
+}
+
+// isJSONPath is true for paths that are JSONPath expressions rather than dot notation.
+func isJSONPath(path string) bool {
+	return strings.HasPrefix(strings.TrimSpace(path), "$")
+}
+
+// compilePath parse a path from the facet path, JSONPath when it starts with $ and dot notation otherwise.
+func compilePath(path string) (selector, error) {
+	if isJSONPath(path) {
+		return parseJSONPath(path)
+	}
+	return dotPath(parsePath(path)), nil
+}
+
+// parsePath split a dot notation path in to its segments.  A dot in a key is written \. and a backslash \\, so
+// "a\.b.c" is the key "a.b" then "c".  \$ is a dollar so a key starting with one isn't read as JSONPath.  An empty path
+// has no segments and finds nothing.
+func parsePath(dotNotation string) []string {
+	if dotNotation == "" {
+		return nil
+	}
+	segments := []string{}
+	var segment strings.Builder
+	for i := 0; i < len(dotNotation); i++ {
+		switch c := dotNotation[i]; {
+		case c == '\\' && i+1 < len(dotNotation) && strings.IndexByte(`.\$`, dotNotation[i+1]) >= 0:
+			i++
+			segment.WriteByte(dotNotation[i])
+		case c == '.':
+			segments = append(segments, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteByte(c)
+		}
+	}
+	return append(segments, segment.String())
+}
+
+// walkPath return every value at the path.  Each segment is a key of an object, an index of an array, or * for every
+// element of an array.  A segment that is missing or doesn't fit the value it is applied to finds nothing.
+func walkPath(value interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		return nil
+	}
+	return walk(value, path, nil)
+}
+
+func walk(value interface{}, path []string, results []interface{}) []interface{} {
+	if len(path) == 0 {
+		if value != nil {
+			results = append(results, value)
+		}
+		return results
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if child, ok := v[path[0]]; ok {
+			return walk(child, path[1:], results)
+		}
+	case []interface{}:
+		if path[0] == wildcard {
+			for _, child := range v {
+				results = walk(child, path[1:], results)
+			}
+			return results
+		}
+		if index, err := strconv.Atoi(path[0]); err == nil && index >= 0 && index < len(v) {
+			return walk(v[index], path[1:], results)
+		}
+	}
+	return results
+}
+
+// flatten replace each array in values with its elements.
+func flatten(values []interface{}) []interface{} {
+	results := []interface{}{}
+	for _, value := range values {
+		if array, ok := value.([]interface{}); ok {
+			results = append(results, array...)
+		} else {
+			results = append(results, value)
+		}
+	}
+	return results
+}
+
+func firstOf(values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// getAtPath return the first value at the path, nil when there isn't one.
+func getAtPath(data map[string]interface{}, path selector) interface{} {
+	return firstOf(path.find(data))
+}
+
+// getAtPathValues return the values at the path with arrays replaced by their elements, so a path to an array and a
+// path fanning out with * both give a list of elements.
+func getAtPathValues(data map[string]interface{}, path selector) []interface{} {
+	return flatten(path.find(data))
+}
+
+// getAtPathString return the first value at the path as a string, "" when it is missing or not a string, number or
+// bool.
+func getAtPathString(data map[string]interface{}, path selector) string {
+	switch v := getAtPath(data, path).(type) {
+	case string:
+		return v
+	case float64, bool:
+		return formatValue(v)
+	}
+	return ""
+}
+
+// getAtPathMap return the entries of every object at the path with the values as strings, the first object with a key
+// gives its value.  Entries holding null, objects or arrays are not values and are skipped.
+func getAtPathMap(data map[string]interface{}, path selector) map[string]string {
+	values := map[string]string{}
+	for _, found := range path.find(data) {
+		object, ok := found.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range object {
+			switch value.(type) {
+			case nil, map[string]interface{}, []interface{}:
+				continue
+			}
+			if _, ok := values[key]; ok {
+				continue
+			}
+			values[key] = formatValue(value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,97 @@
+package facetengine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePath(t *testing.T) {
+	require.Nil(t, parsePath(""))
+	require.Equal(t, []string{"a"}, parsePath("a"))
+	require.Equal(t, []string{"a", "*", "0", "b"}, parsePath("a.*.0.b"))
+	require.Equal(t, []string{"a.b", "c"}, parsePath(`a\.b.c`))
+	require.Equal(t, []string{`a\`, "b"}, parsePath(`a\\.b`))
+	require.Equal(t, []string{`a\b`}, parsePath(`a\b`))
+	require.Equal(t, []string{"a", ""}, parsePath("a."))
+	require.Equal(t, []string{"$oid"}, parsePath(`\$oid`))
+}
+
+func TestWalkPath(t *testing.T) {
+	var data map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(`{
+		"items": [{"size": 1, "tags": ["a", "b"]}, {"size": 2}, "not an object", {"size": null}],
+		"dotted.key": {"x": "y"},
+		"name": "n",
+		"count": 3
+	}`), &data))
+	require.Equal(t, []interface{}{1.0}, walkPath(data, parsePath("items.0.size")))
+	require.Equal(t, []interface{}{2.0}, walkPath(data, parsePath("items.1.size")))
+	require.Equal(t, []interface{}{1.0, 2.0}, walkPath(data, parsePath("items.*.size")))
+	require.Equal(t, []interface{}{"a", "b"}, getAtPathValues(data, dotPath(parsePath("items.*.tags"))))
+	require.Equal(t, []interface{}{"y"}, walkPath(data, parsePath(`dotted\.key.x`)))
+	require.Equal(t, 4, len(getAtPathValues(data, dotPath(parsePath("items")))))
+
+	// missing and mistyped segments find nothing rather than panic.
+	for _, path := range []string{"", "missing", "items.9.size", "items.-1", "items.size", "name.first", "count.*", "items.2.size", "items.3.size", "dotted.key.x"} {
+		require.Empty(t, walkPath(data, parsePath(path)), path)
+		require.Nil(t, getAtPath(data, dotPath(parsePath(path))), path)
+		require.Equal(t, "", getAtPathString(data, dotPath(parsePath(path))), path)
+		require.Empty(t, getAtPathMap(data, dotPath(parsePath(path))), path)
+	}
+	require.Equal(t, "3", getAtPathString(data, dotPath(parsePath("count"))))
+	require.Equal(t, "", getAtPathString(data, dotPath(parsePath("items"))))
+	require.Equal(t, map[string]string{"size": "1"}, getAtPathMap(data, dotPath(parsePath("items.*"))))
+}
+
+func TestWildcardPaths(t *testing.T) {
+	facetPath := &FacetPath{
+		IDDotNotation:        "meta.ids.0",
+		ArrayDotNotation:     "parts.*.measurements",
+		NameFieldDotNotation: "names.0",
+		NameMetaDotNotation:  `metrics.metric\.name`,
+		ValueMapDotNotation:  "metrics.values",
+		Fields:               []*FieldFacet{{DotNotation: "parts.*.weight", Group: "part"}},
+	}
+	_, facetGroups, err := NewFacetEngine(`[
+		{"meta": {"ids": [7]}, "parts": [
+			{"weight": 1, "measurements": [{"names": ["area"], "metrics": {"metric.name": "cube", "values": {"side": 10}}}]},
+			{"weight": 2, "measurements": [{"names": ["area"], "metrics": {"metric.name": "sphere", "values": {"diameter": "4", "ignored": {"nested": 1}}}}]},
+			{"measurements": ["not an object", {"names": "not an array", "metrics": 5}]}
+		]},
+		{"meta": {"ids": ["8"]}, "parts": [{"measurements": {"names": ["area"], "metrics": {"metric.name": "cube", "values": {"side": 20}}}}]}
+	]`, facetPath)
+	require.Nil(t, err)
+	require.Equal(t, 2, facetGroups["area (cube)"].Count)
+	require.Equal(t, map[string]int{"10": 1, "20": 1}, facetGroups["area (cube)"].Facets["side"].Counts)
+	require.Equal(t, 1, len(facetGroups["area (sphere)"].Facets))
+	require.Equal(t, map[string]int{"1": 1, "2": 1}, facetGroups["part"].Facets["weight"].Counts)
+
+	_, _, err = NewFacetEngine(`[{"meta": {"ids": [7]}, "parts": [{"weight": 1}, {"weight": "x"}]}]`, facetPath)
+	require.Equal(t, "parts.*.weight.1", ToError(err).Path)
+	_, _, err = NewFacetEngine(`[{"meta": {"ids": "7"}}]`, facetPath)
+	require.Equal(t, ErrMissingID, ToError(err).Code)
+}
+
+func TestLargeNumbers(t *testing.T) {
+	var data map[string]interface{}
+	require.Nil(t, json.Unmarshal([]byte(`{"id": 1234567, "values": {"big": 25000000, "small": 0.000001}}`), &data))
+	require.Equal(t, "1234567", getAtPathString(data, dotPath(parsePath("id"))))
+	require.Equal(t, map[string]string{"big": "25000000", "small": "0.000001"}, getAtPathMap(data, dotPath(parsePath("values"))))
+
+	facetPath := &FacetPath{ArrayDotNotation: "parts", NameFieldDotNotation: "name", NameMetaDotNotation: "kind", ValueMapDotNotation: "values"}
+	facetEngine, facetGroups, err := NewFacetEngine(`[
+		{"id": 1234567, "parts": [{"name": "area", "kind": "cube", "values": {"side": 25000000}}]},
+		{"id": 2, "parts": [{"name": "area", "kind": "cube", "values": {"side": 3}}]}
+	]`, facetPath)
+	require.Nil(t, err)
+	require.Equal(t, map[string]int{"25000000": 1, "3": 1}, facetGroups["area (cube)"].Facets["side"].Counts)
+	ids, _, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"1234567", "2"}, ids)
+	require.Nil(t, facetEngine.DeleteRecords([]string{"1234567"}))
+	ids, _, err = facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, []string{"2"}, ids)
+}
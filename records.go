@@ -0,0 +1,169 @@
+package facetengine
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AddRecords take a json string representation of an array of new objects and add them to the facets without
+// reinitializing.  Records must have ids that are not already in the engine.
+func (f *FacetEngine) AddRecords(jsonData string) error {
+	var genericObjects []map[string]interface{}
+	err := json.Unmarshal([]byte(jsonData), &genericObjects)
+	if err != nil {
+		return ToError(err)
+	}
+	return f.AddObjects(genericObjects)
+}
+
+// UpdateRecord take a json string representation of an object and replace the record that has the same id.
+func (f *FacetEngine) UpdateRecord(jsonData string) error {
+	var genericObject map[string]interface{}
+	err := json.Unmarshal([]byte(jsonData), &genericObject)
+	if err != nil {
+		return ToError(err)
+	}
+	return f.UpdateObject(genericObject)
+}
+
+// UpdateObject replace the record that has the same id as the already decoded object.
+func (f *FacetEngine) UpdateObject(genericObject map[string]interface{}) error {
+	if !f.initialized || f.facetPath == nil {
+		return NewError(ErrNotInitialized, "must initialize before updating records")
+	}
+	id, err := f.recordID(genericObject)
+	if err != nil {
+		return err
+	}
+	if !f.allIds.Contains(id) {
+		return &Error{Code: ErrUnknownRecord, Message: fmt.Sprintf("no record with id %s", id), RecordID: id}
+	}
+	if _, err := f.extract(id, genericObject); err != nil {
+		return err
+	}
+	if err := f.DeleteRecords([]string{id}); err != nil {
+		return err
+	}
+	return f.AddObjects([]map[string]interface{}{genericObject})
+}
+
+// DeleteRecords remove the records with the ids from the facets without reinitializing.
+func (f *FacetEngine) DeleteRecords(ids []string) error {
+	deleted := map[string]bool{}
+	for _, id := range ids {
+		if !f.allIds.Contains(id) {
+			return &Error{Code: ErrUnknownRecord, Message: fmt.Sprintf("no record with id %s", id), RecordID: id}
+		}
+		deleted[id] = true
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+	ordinals := NewBitmap()
+	for id := range deleted {
+		ordinals.Add(f.dictionary.ordinals[id])
+	}
+	for key, column := range f.RecordLookup {
+		column.remove(ordinals)
+		if column.Len() == 0 {
+			delete(f.RecordLookup, key)
+		}
+	}
+	f.RecordLookup.sort()
+	for id := range deleted {
+		delete(f.records, f.dictionary.ordinals[id])
+		f.allIds.Remove(id)
+		f.ids.Remove(id)
+	}
+	return nil
+}
+
+// AddObjects add already decoded objects, checking all of them are valid before adding any of them.
+func (f *FacetEngine) AddObjects(genericObjects []map[string]interface{}) error {
+	if !f.initialized || f.facetPath == nil {
+		return NewError(ErrNotInitialized, "must initialize before adding records")
+	}
+	return f.ingest(genericObjects, true)
+}
+
+// ingest index the objects and sort the columns they were added to.  Along with DeleteRecords and RestoreSnapshot this
+// is the only place the index is changed, queries only read it.
+func (f *FacetEngine) ingest(genericObjects []map[string]interface{}, unique bool) error {
+	err := f.index(genericObjects, unique)
+	f.RecordLookup.sort()
+	return err
+}
+
+// index extract the facet values of the objects in to the columns, checking all of them are valid before adding any of
+// them.  When unique is set the ids must not already be in the engine.  The columns are left unsorted.
+func (f *FacetEngine) index(genericObjects []map[string]interface{}, unique bool) error {
+	ids := make([]string, len(genericObjects))
+	values := make([][]*extractedValue, len(genericObjects))
+	added := map[string]bool{}
+	for i, genericObject := range genericObjects {
+		id, err := f.recordID(genericObject)
+		if err != nil {
+			return err
+		}
+		if unique && (f.allIds.Contains(id) || added[id]) {
+			return &Error{Code: ErrDuplicateRecord, Message: fmt.Sprintf("record with id %s already exists", id), RecordID: id}
+		}
+		added[id] = true
+		ids[i] = id
+		values[i], err = f.extract(id, genericObject)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range genericObjects {
+		f.allIds.Add(ids[i])
+		f.ids.Add(ids[i])
+		ordinal := f.dictionary.intern(ids[i])
+		if f.facetPath.RetainRecords {
+			f.records[ordinal] = genericObjects[i]
+		}
+		// a record can have the same value for a facet more than once, even under keys that only differ by case, it is
+		// only indexed once.
+		indexed := map[indexedValue]bool{}
+		for _, value := range values[i] {
+			f.labelGroup(value)
+			key := indexedValue{key: FacetKey{Group: value.group, Facet: value.facet}, value: value.value}
+			if indexed[key] {
+				continue
+			}
+			indexed[key] = true
+			f.RecordLookup.Add(value, ordinal)
+		}
+	}
+	return nil
+}
+
+// indexedValue is a value of a facet, whatever labels it was found with.
+type indexedValue struct {
+	key   FacetKey
+	value string
+}
+
+// labelGroup give the value the label its group was first found with.
+func (f *FacetEngine) labelGroup(value *extractedValue) {
+	if f.groupLabels == nil {
+		f.groupLabels = map[string]string{}
+	}
+	if label, ok := f.groupLabels[value.group]; ok {
+		value.groupLabel = label
+	} else {
+		f.groupLabels[value.group] = value.groupLabel
+	}
+}
+
+// Record return the object a record was indexed from.  Objects are only kept when FacetPath.RetainRecords is set.
+func (f *FacetEngine) Record(id string) (map[string]interface{}, error) {
+	if !f.allIds.Contains(id) {
+		return nil, &Error{Code: ErrUnknownRecord, Message: fmt.Sprintf("no record with id %s", id), RecordID: id}
+	}
+	genericObject, ok := f.records[f.dictionary.ordinals[id]]
+	if !ok {
+		return nil, &Error{Code: ErrUnknownRecord, Message: fmt.Sprintf("record %s was not retained, set retainRecords in the configuration", id), RecordID: id}
+	}
+	return genericObject, nil
+}
@@ -0,0 +1,122 @@
+package facetengine
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddRecords(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+"]", defaultFacetPath)
+	facetEngine.AddFilter("total-area (hex-cylinder)", "diameter", Inclusive(16), Inclusive(16))
+	listOfIds, _, _ := facetEngine.Query()
+	require.Empty(t, listOfIds)
+
+	err := facetEngine.AddRecords("[" + object2 + "," + object3 + "]")
+	require.Nil(t, err)
+	listOfIds, facetGroups, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"2", "3"}, listOfIds)
+	require.Equal(t, map[string]int{"16": 2}, facetGroups["total-area (hex-cylinder)"].Facets["diameter"].Counts)
+
+	facetEngine.ClearFilters()
+	listOfIds, facetGroups, _ = facetEngine.Query()
+	require.ElementsMatch(t, []string{"1", "2", "3"}, listOfIds)
+	require.Equal(t, map[string]int{"15": 1, "16": 2}, facetGroups["total-area (hex-cylinder)"].Facets["diameter"].Counts)
+}
+
+func TestAddRecordsErrors(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+"]", defaultFacetPath)
+	require.Error(t, facetEngine.AddRecords("NOTJSON"))
+	require.Error(t, facetEngine.AddRecords("["+object1+"]"))
+	require.Error(t, facetEngine.AddRecords("["+object2+","+object2+"]"))
+	require.Error(t, facetEngine.AddRecords("["+object8+"]"))
+	require.Error(t, facetEngine.AddRecords("["+object2+","+object9+"]"))
+	listOfIds, _, _ := facetEngine.Query()
+	require.ElementsMatch(t, []string{"1"}, listOfIds)
+
+	uninitialized := &FacetEngine{}
+	require.Error(t, uninitialized.AddRecords("["+object2+"]"))
+}
+
+func TestAddRecordsToEmpty(t *testing.T) {
+	facetEngine, _, err := NewFacetEngine("", defaultFacetPath)
+	require.Nil(t, err)
+	require.Nil(t, facetEngine.AddRecords("["+object1+"]"))
+	listOfIds, facetGroups, _ := facetEngine.Query()
+	require.ElementsMatch(t, []string{"1"}, listOfIds)
+	require.Equal(t, 3, len(facetGroups))
+}
+
+func TestUpdateRecord(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine(readmeExample, readmeFacetPath)
+	facetEngine.AddFilter("area (cube)", "side", Inclusive(8), Inclusive(12))
+	listOfIds, _, _ := facetEngine.Query()
+	require.ElementsMatch(t, []string{"record 1"}, listOfIds)
+
+	err := facetEngine.UpdateRecord(`{"id": "record 2", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "11"}}}]}`)
+	require.Nil(t, err)
+	listOfIds, facetGroups, _ := facetEngine.Query()
+	require.ElementsMatch(t, []string{"record 1", "record 2"}, listOfIds)
+	require.Equal(t, map[string]int{"10": 1, "11": 1}, facetGroups["area (cube)"].Facets["side"].Counts)
+
+	require.Error(t, facetEngine.UpdateRecord(`{"id": "record 3"}`))
+	require.Error(t, facetEngine.UpdateRecord(`{"name": "record 3"}`))
+	require.Error(t, facetEngine.UpdateRecord(`NOTJSON`))
+	require.Error(t, facetEngine.UpdateRecord(`{"id": "record 2", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "eleven"}}}]}`))
+	listOfIds, _, _ = facetEngine.Query()
+	require.ElementsMatch(t, []string{"record 1", "record 2"}, listOfIds)
+}
+
+func TestDeleteRecords(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	require.Error(t, facetEngine.DeleteRecords([]string{"1", "missing"}))
+	require.Nil(t, facetEngine.DeleteRecords([]string{}))
+	require.Nil(t, facetEngine.DeleteRecords([]string{"1", "3"}))
+	listOfIds, facetGroups, _ := facetEngine.Query()
+	require.ElementsMatch(t, []string{"2"}, listOfIds)
+	require.Equal(t, 1, len(facetGroups))
+	require.Nil(t, facetEngine.RecordLookup[FacetKey{Group: "shaft (screwthread)", Facet: "pitch"}])
+
+	facetEngine.AddFilter("total-area (hex-cylinder)", "diameter", Inclusive(0), Inclusive(100))
+	listOfIds, _, _ = facetEngine.Query()
+	require.ElementsMatch(t, []string{"2"}, listOfIds)
+	require.Error(t, facetEngine.DeleteRecords([]string{"1"}))
+}
+
+// requireSorted check every numeric column is sorted, so queries have nothing to write.
+func requireSorted(t *testing.T, facetEngine *FacetEngine) {
+	for key, column := range facetEngine.RecordLookup {
+		if column.Categorical {
+			continue
+		}
+		require.Equal(t, column.Len(), column.sorted, key)
+		require.True(t, sort.SliceIsSorted(column.Numbers, func(i, j int) bool { return numberLess(column.Numbers[i], column.Numbers[j]) }), key)
+	}
+}
+
+func TestChangesLeaveColumnsSorted(t *testing.T) {
+	facetEngine, _, err := NewFacetEngine("["+object3+","+object1+"]", defaultFacetPath)
+	require.Nil(t, err)
+	requireSorted(t, facetEngine)
+	require.Nil(t, facetEngine.AddRecords("["+object2+"]"))
+	requireSorted(t, facetEngine)
+	require.Nil(t, facetEngine.DeleteRecords([]string{"3"}))
+	requireSorted(t, facetEngine)
+
+	restored := newFacetEngine()
+	_, err = restored.RestoreSnapshot(facetEngine.Snapshot())
+	require.Nil(t, err)
+	requireSorted(t, restored)
+
+	streamed, _, err := NewFacetEngine("", readmeFacetPath)
+	require.Nil(t, err)
+	ingester, err := streamed.NewIngester(nil)
+	require.Nil(t, err)
+	_, err = ingester.Write([]byte(ndjsonExample))
+	require.Nil(t, err)
+	requireSorted(t, streamed)
+	require.Nil(t, ingester.Close())
+	requireSorted(t, streamed)
+}
@@ -0,0 +1,103 @@
+package facetengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var rulesFacetPath = &FacetPath{
+	ArrayDotNotation:     "bounds",
+	NameFieldDotNotation: "name",
+	NameMetaDotNotation:  "boundingType.name",
+	ValueMapDotNotation:  "boundingType.measurements",
+	Rules: []*ExtractionRule{
+		{
+			ArrayDotNotation:     "measurements",
+			NameFieldDotNotation: "measurementName",
+			NameMetaDotNotation:  "metrics.metricName",
+			ValueMapDotNotation:  "metrics.measurements",
+		},
+		{
+			ArrayDotNotation:    "labels",
+			NameMetaDotNotation: "kind",
+			ValueMapDotNotation: "values",
+			GroupName:           "Label {meta}",
+		},
+	},
+}
+
+var rulesExample = `[
+	{"id": "1",
+		"bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "1.5"}}}],
+		"measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "10"}}}],
+		"labels": [{"kind": "Size", "values": {"weight": "3"}}]},
+	{"id": "2",
+		"bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "2"}}}],
+		"measurements": [{"measurementName": "shaft", "metrics": {"metricName": "screwthread", "measurements": {"pitch": "2", "height": "4"}}}],
+		"labels": [{"values": {"weight": "5"}}]}
+]`
+
+func TestExtractionRules(t *testing.T) {
+	facetEngine, facetGroups, err := NewFacetEngine(rulesExample, rulesFacetPath)
+	require.Nil(t, err)
+	require.Equal(t, 3, len(facetGroups))
+	// both rules find shaft (screwthread) in record 2, the groups merge and the same value is only counted once.
+	shaft := facetGroups["shaft (screwthread)"]
+	require.Equal(t, 2, shaft.Count)
+	require.Equal(t, map[string]int{"1.5": 1, "2": 1}, shaft.Facets["pitch"].Counts)
+	require.Equal(t, 1, shaft.Facets["height"].Count)
+	require.Equal(t, 1, facetGroups["area (cube)"].Count)
+	// the second label has no kind so it is skipped, the template doesn't use a name so none is needed.
+	require.Equal(t, map[string]int{"3": 1}, facetGroups["label size"].Facets["weight"].Counts)
+
+	_, err = facetEngine.AddFilter("label size", "weight", Inclusive(0), Inclusive(10))
+	require.Nil(t, err)
+	ids, _, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, []string{"1"}, ids)
+}
+
+func TestExtractionRulesOnly(t *testing.T) {
+	facetPath := &FacetPath{Rules: rulesFacetPath.Rules}
+	_, facetGroups, err := NewFacetEngine(rulesExample, facetPath)
+	require.Nil(t, err)
+	require.Equal(t, 1, facetGroups["shaft (screwthread)"].Count)
+	require.Equal(t, 2, len(facetGroups["shaft (screwthread)"].Facets))
+	require.NotNil(t, facetGroups["label size"])
+}
+
+func TestExtractionRuleErrors(t *testing.T) {
+	facetPath := &FacetPath{Rules: []*ExtractionRule{{ArrayDotNotation: "labels", NameMetaDotNotation: "kind", ValueMapDotNotation: "values", GroupName: "{meta}"}}}
+	_, _, err := NewFacetEngine(`[{"id": "1", "labels": [{"kind": "size", "values": {"weight": "heavy"}}]}]`, facetPath)
+	require.Equal(t, ErrInvalidNumber, ToError(err).Code)
+	require.Equal(t, "labels.0.values.weight", ToError(err).Path)
+}
+
+func TestGroupName(t *testing.T) {
+	rule := &ExtractionRule{}
+	name, ok := rule.groupName("Area", "Cube")
+	require.True(t, ok)
+	require.Equal(t, "Area (Cube)", name)
+	_, ok = rule.groupName("Area", " ")
+	require.False(t, ok)
+
+	rule.GroupName = "{meta}/{name}/{meta}"
+	name, ok = rule.groupName("a", "b")
+	require.True(t, ok)
+	require.Equal(t, "b/a/b", name)
+
+	rule.GroupName = "fixed"
+	name, ok = rule.groupName("", "")
+	require.True(t, ok)
+	require.Equal(t, "fixed", name)
+}
+
+func TestSnapshotRules(t *testing.T) {
+	facetEngine, _, err := NewFacetEngine(rulesExample, rulesFacetPath)
+	require.Nil(t, err)
+	restored := newFacetEngine()
+	_, err = restored.RestoreSnapshot(facetEngine.Snapshot())
+	require.Nil(t, err)
+	require.Equal(t, rulesFacetPath, restored.facetPath)
+}
@@ -1,48 +1,78 @@
-package main
+package facetengine
 
 import (
 	"encoding/json"
 	"sort"
 )
 
-// Set simple set type
+// Set simple set type.  Values are interned to dense integers by a dictionary, which can be shared between sets, and
+// the set is a bitmap of those integers.
 type Set struct {
-	list map[string]struct{}
+	dictionary *dictionary
+	bitmap     *Bitmap
+}
+
+// dictionary give each string a dense integer in the order they are first seen.  Integers are never reused.
+type dictionary struct {
+	ordinals map[string]uint32
+	values   []string
+}
+
+func newDictionary() *dictionary {
+	return &dictionary{ordinals: map[string]uint32{}}
+}
+
+// intern return the ordinal of the value, giving it the next one if it doesn't already have one.
+func (d *dictionary) intern(value string) uint32 {
+	if ordinal, ok := d.ordinals[value]; ok {
+		return ordinal
+	}
+	ordinal := uint32(len(d.values))
+	d.ordinals[value] = ordinal
+	d.values = append(d.values, value)
+	return ordinal
 }
 
 // Len return the length of the set
 func (s *Set) Len() int {
-	return len(s.list)
+	return s.bitmap.Len()
 }
 
 // Contains does this value exist in the set.
 func (s *Set) Contains(v string) bool {
-	_, ok := s.list[v]
-	return ok
+	ordinal, ok := s.dictionary.ordinals[v]
+	return ok && s.bitmap.Contains(ordinal)
 }
 
 // Add a value
 func (s *Set) Add(v string) {
-	s.list[v] = struct{}{}
+	s.bitmap.Add(s.dictionary.intern(v))
 }
 
-// ToArray return the values
-func (s *Set) ToArray() []string {
-	keys := make([]string, len(s.list))
-
-	i := 0
-	for k := range s.list {
-		keys[i] = k
-		i++
+// Remove a value
+func (s *Set) Remove(v string) {
+	if ordinal, ok := s.dictionary.ordinals[v]; ok {
+		s.bitmap.Remove(ordinal)
 	}
+}
+
+// ToArray return the values in the order they were first added
+func (s *Set) ToArray() []string {
+	keys := make([]string, 0, s.bitmap.Len())
+	s.bitmap.ForEach(func(ordinal uint32) {
+		keys = append(keys, s.dictionary.values[ordinal])
+	})
 	return keys
 }
 
 // NewSet new set.
 func NewSet() *Set {
-	s := &Set{}
-	s.list = make(map[string]struct{})
-	return s
+	return newSetOf(newDictionary(), NewBitmap())
+}
+
+// newSetOf a set of the values in the bitmap that shares the dictionary.
+func newSetOf(dictionary *dictionary, bitmap *Bitmap) *Set {
+	return &Set{dictionary: dictionary, bitmap: bitmap}
 }
 
 // MarshalJSON Set is optimized for memory usage and lookup but is really a list of unique elements.
@@ -59,7 +89,7 @@ func (s *Set) UnmarshalJSON(j []byte) error {
 	if err != nil {
 		return err
 	}
-	s.list = map[string]struct{}{}
+	*s = *NewSet()
 	for _, item := range list {
 		s.Add(item)
 	}
@@ -0,0 +1,426 @@
+package facetengine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// snapshotMagic starts every snapshot so that other data is rejected rather than misread.
+const snapshotMagic = "FESN"
+
+// snapshotVersion is written after the magic and bumped whenever the layout changes.  Older versions are rejected and
+// the index rebuilt from the records.
+const snapshotVersion = 6
+
+// Snapshot encode the index in a compact binary form that RestoreSnapshot turns back in to an engine without going
+// through the records again.  It holds the FacetPath, the record ids and ordinals, and every Column from which the
+// facet groups are computed.  Filters and retained records are not included.
+//
+// The layout is the magic and a uvarint version followed by uvarints, length prefixed strings and little endian
+// float64s.  The group and facet keys and labels and the values of the columns are written once in a string table and
+// referred to by index.
+func (f *FacetEngine) Snapshot() []byte {
+	w := &snapshotWriter{}
+	w.buf.WriteString(snapshotMagic)
+	w.uvarint(snapshotVersion)
+	w.bool(f.initialized)
+	w.facetPath(f.facetPath)
+	w.strings(f.dictionary.values)
+	w.bitmap(f.allIds.bitmap)
+
+	table := map[string]uint64{}
+	var values []string
+	intern := func(value string) uint64 {
+		index, ok := table[value]
+		if !ok {
+			index = uint64(len(values))
+			table[value] = index
+			values = append(values, value)
+		}
+		return index
+	}
+	// sorted so the same index always gives the same bytes.
+	keys := make([]FacetKey, 0, len(f.RecordLookup))
+	for key := range f.RecordLookup {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Group != keys[j].Group {
+			return keys[i].Group < keys[j].Group
+		}
+		return keys[i].Facet < keys[j].Facet
+	})
+	columns := &snapshotWriter{}
+	columns.uvarint(uint64(len(keys)))
+	for _, key := range keys {
+		column := f.RecordLookup[key]
+		columns.uvarint(intern(column.Group))
+		columns.uvarint(intern(column.Facet))
+		columns.uvarint(intern(column.GroupLabel))
+		columns.uvarint(intern(column.FacetLabel))
+		columns.bool(column.Categorical)
+		columns.uvarint(uint64(column.Len()))
+		for i, ordinal := range column.Ordinals {
+			columns.uvarint(uint64(ordinal))
+			columns.uvarint(intern(column.Values[i]))
+			if !column.Categorical {
+				columns.float64(column.Numbers[i])
+			}
+		}
+	}
+	w.strings(values)
+	w.buf.Write(columns.buf.Bytes())
+	return w.buf.Bytes()
+}
+
+// RestoreSnapshot replace the index with one from Snapshot and return the facets of all the records.  Filters are
+// cleared.  Retained records are not part of a snapshot, so RetainRecords is turned off in the restored FacetPath.  A
+// snapshot that is corrupt or from another version returns an error and leaves the engine as it was.
+func (f *FacetEngine) RestoreSnapshot(snapshot []byte) (map[string]*FacetGroup, error) {
+	r := &snapshotReader{data: snapshot}
+	if !bytes.HasPrefix(snapshot, []byte(snapshotMagic)) {
+		return nil, NewError(ErrInvalidArgument, "not a facet engine snapshot")
+	}
+	r.offset = len(snapshotMagic)
+	if version := r.uvarint(); r.err == nil && version != snapshotVersion {
+		return nil, NewError(ErrInvalidArgument, "snapshot version %d is not supported, expected %d", version, snapshotVersion)
+	}
+	initialized := r.bool()
+	facetPath := r.facetPath()
+	if r.err == nil && facetPath != nil {
+		if err := facetPath.validate(); err != nil {
+			r.fail("invalid facet path, " + err.Error())
+		}
+	}
+	dictionary := newDictionary()
+	for _, id := range r.strings() {
+		dictionary.intern(id)
+	}
+	if r.err == nil && len(dictionary.ordinals) != len(dictionary.values) {
+		r.fail("duplicate record id")
+	}
+	allIds := r.bitmap()
+	if ordinals := allIds.ToArray(); len(ordinals) > 0 && int(ordinals[len(ordinals)-1]) >= len(dictionary.values) {
+		r.fail("record ordinal out of range")
+	}
+	values := r.strings()
+	lookup := RecordLookup{}
+	groupLabels := map[string]string{}
+	count := r.length(5)
+	for i := 0; i < count && r.err == nil; i++ {
+		column := &Column{
+			Group:       r.index(values),
+			Facet:       r.index(values),
+			GroupLabel:  r.index(values),
+			FacetLabel:  r.index(values),
+			Categorical: r.bool(),
+		}
+		entries := r.length(2)
+		for k := 0; k < entries && r.err == nil; k++ {
+			ordinal := r.uvarint()
+			if ordinal > math.MaxUint32 || !allIds.Contains(uint32(ordinal)) {
+				r.fail("column entry for unknown ordinal")
+			}
+			value := r.index(values)
+			number := 0.0
+			if !column.Categorical {
+				number = r.float64()
+			}
+			column.add(value, number, uint32(ordinal))
+		}
+		lookup[FacetKey{Group: column.Group, Facet: column.Facet}] = column
+		groupLabels[column.Group] = column.GroupLabel
+	}
+	if r.err == nil && r.offset != len(r.data) {
+		r.fail("unexpected data after the columns")
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	lookup.sort()
+	if facetPath != nil {
+		facetPath.RetainRecords = false
+	}
+	f.RecordLookup = lookup
+	f.groupLabels = groupLabels
+	f.records = map[uint32]map[string]interface{}{}
+	f.facetPath = facetPath
+	f.dictionary = dictionary
+	f.allIds = newSetOf(dictionary, allIds)
+	f.initialized = initialized
+	f.ClearFilters()
+	return f.GetFacets()
+}
+
+// snapshotWriter appends the parts of a snapshot.
+type snapshotWriter struct {
+	buf     bytes.Buffer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (w *snapshotWriter) uvarint(value uint64) {
+	n := binary.PutUvarint(w.scratch[:], value)
+	w.buf.Write(w.scratch[:n])
+}
+
+func (w *snapshotWriter) bool(value bool) {
+	if value {
+		w.buf.WriteByte(1)
+	} else {
+		w.buf.WriteByte(0)
+	}
+}
+
+func (w *snapshotWriter) float64(value float64) {
+	binary.LittleEndian.PutUint64(w.scratch[:8], math.Float64bits(value))
+	w.buf.Write(w.scratch[:8])
+}
+
+func (w *snapshotWriter) string(value string) {
+	w.uvarint(uint64(len(value)))
+	w.buf.WriteString(value)
+}
+
+func (w *snapshotWriter) strings(values []string) {
+	w.uvarint(uint64(len(values)))
+	for _, value := range values {
+		w.string(value)
+	}
+}
+
+// facetPath write a present flag followed by the fields in declaration order.
+func (w *snapshotWriter) facetPath(facetPath *FacetPath) {
+	w.bool(facetPath != nil)
+	if facetPath == nil {
+		return
+	}
+	w.string(facetPath.IDDotNotation)
+	w.string(facetPath.ArrayDotNotation)
+	w.string(facetPath.NameMetaDotNotation)
+	w.string(facetPath.NameFieldDotNotation)
+	w.string(facetPath.ValueMapDotNotation)
+	w.uvarint(uint64(len(facetPath.Rules)))
+	for _, rule := range facetPath.Rules {
+		if rule == nil {
+			rule = &ExtractionRule{}
+		}
+		w.string(rule.ArrayDotNotation)
+		w.string(rule.NameMetaDotNotation)
+		w.string(rule.NameFieldDotNotation)
+		w.string(rule.ValueMapDotNotation)
+		w.string(rule.GroupName)
+	}
+	w.uvarint(uint64(len(facetPath.Fields)))
+	for _, field := range facetPath.Fields {
+		w.string(field.DotNotation)
+		w.string(field.Group)
+		w.string(field.Facet)
+	}
+	w.strings(facetPath.CategoricalFacets)
+	w.uvarint(uint64(len(facetPath.CategoricalFacetKeys)))
+	for _, key := range facetPath.CategoricalFacetKeys {
+		w.string(key.Group)
+		w.string(key.Facet)
+	}
+	w.bool(facetPath.RetainRecords)
+	w.string(facetPath.GroupName)
+	w.string(facetPath.CaseFolding)
+}
+
+// bitmap write the number of chunks then each chunk's key and words.
+func (w *snapshotWriter) bitmap(b *Bitmap) {
+	w.uvarint(uint64(len(b.chunks)))
+	for _, c := range b.chunks {
+		w.uvarint(uint64(c.key))
+		w.uvarint(uint64(len(c.words)))
+		for _, word := range c.words {
+			w.uvarint(word)
+		}
+	}
+}
+
+// snapshotReader reads the parts written by snapshotWriter.  The first failure is kept in err and every read after it
+// returns a zero value, so callers check err once they are done.
+type snapshotReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (r *snapshotReader) fail(message string) {
+	if r.err == nil {
+		r.err = NewError(ErrInvalidArgument, "corrupt snapshot: %s at byte %d", message, r.offset)
+	}
+}
+
+func (r *snapshotReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(r.data[r.offset:])
+	if n <= 0 {
+		r.fail("bad integer")
+		return 0
+	}
+	r.offset += n
+	return value
+}
+
+// length read a count of items that each take at least minBytes, failing if there aren't enough bytes left for them.
+func (r *snapshotReader) length(minBytes int) int {
+	length := r.uvarint()
+	if length > uint64(len(r.data)-r.offset)/uint64(minBytes) {
+		r.fail("length past the end")
+		return 0
+	}
+	return int(length)
+}
+
+func (r *snapshotReader) bool() bool {
+	if r.err != nil {
+		return false
+	}
+	if r.offset >= len(r.data) || r.data[r.offset] > 1 {
+		r.fail("bad flag")
+		return false
+	}
+	r.offset++
+	return r.data[r.offset-1] == 1
+}
+
+func (r *snapshotReader) float64() float64 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.data)-r.offset < 8 {
+		r.fail("number past the end")
+		return 0
+	}
+	value := math.Float64frombits(binary.LittleEndian.Uint64(r.data[r.offset:]))
+	r.offset += 8
+	return value
+}
+
+func (r *snapshotReader) string() string {
+	length := r.length(1)
+	if r.err != nil {
+		return ""
+	}
+	value := string(r.data[r.offset : r.offset+length])
+	r.offset += length
+	return value
+}
+
+// strings read a list written by snapshotWriter.strings, an empty list is nil as it was before it was written.
+func (r *snapshotReader) strings() []string {
+	count := r.length(1)
+	if count == 0 {
+		return nil
+	}
+	values := make([]string, count)
+	for i := range values {
+		values[i] = r.string()
+	}
+	return values
+}
+
+// index read a reference in to the string table.
+func (r *snapshotReader) index(values []string) string {
+	index := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if index >= uint64(len(values)) {
+		r.fail("string index out of range")
+		return ""
+	}
+	return values[index]
+}
+
+func (r *snapshotReader) facetPath() *FacetPath {
+	if !r.bool() {
+		return nil
+	}
+	return &FacetPath{
+		IDDotNotation:        r.string(),
+		ArrayDotNotation:     r.string(),
+		NameMetaDotNotation:  r.string(),
+		NameFieldDotNotation: r.string(),
+		ValueMapDotNotation:  r.string(),
+		Rules:                r.rules(),
+		Fields:               r.fields(),
+		CategoricalFacets:    r.strings(),
+		CategoricalFacetKeys: r.facetKeys(),
+		RetainRecords:        r.bool(),
+		GroupName:            r.string(),
+		CaseFolding:          r.string(),
+	}
+}
+
+func (r *snapshotReader) facetKeys() []FacetKey {
+	count := r.length(2)
+	if count == 0 {
+		return nil
+	}
+	keys := make([]FacetKey, count)
+	for i := range keys {
+		keys[i] = FacetKey{Group: r.string(), Facet: r.string()}
+	}
+	return keys
+}
+
+func (r *snapshotReader) rules() []*ExtractionRule {
+	count := r.length(5)
+	if count == 0 {
+		return nil
+	}
+	rules := make([]*ExtractionRule, count)
+	for i := range rules {
+		rules[i] = &ExtractionRule{
+			ArrayDotNotation:     r.string(),
+			NameMetaDotNotation:  r.string(),
+			NameFieldDotNotation: r.string(),
+			ValueMapDotNotation:  r.string(),
+			GroupName:            r.string(),
+		}
+	}
+	return rules
+}
+
+func (r *snapshotReader) fields() []*FieldFacet {
+	count := r.length(3)
+	if count == 0 {
+		return nil
+	}
+	fields := make([]*FieldFacet, count)
+	for i := range fields {
+		fields[i] = &FieldFacet{DotNotation: r.string(), Group: r.string(), Facet: r.string()}
+	}
+	return fields
+}
+
+func (r *snapshotReader) bitmap() *Bitmap {
+	b := NewBitmap()
+	chunks := r.length(2)
+	for i := 0; i < chunks && r.err == nil; i++ {
+		key := r.uvarint()
+		count := r.length(1)
+		if count > chunkBits/64 {
+			r.fail("chunk has too many words")
+			count = 0
+		}
+		words := make([]uint64, count)
+		for k := range words {
+			words[k] = r.uvarint()
+		}
+		if key > math.MaxUint16 || (len(b.chunks) > 0 && uint16(key) <= b.chunks[len(b.chunks)-1].key) {
+			r.fail("chunks out of order")
+		}
+		if r.err == nil {
+			b.appendChunk(uint16(key), words)
+		}
+	}
+	return b
+}
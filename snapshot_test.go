@@ -0,0 +1,130 @@
+package facetengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	facetEngine, facetGroups, err := NewFacetEngine(categoricalExample, categoricalFacetPath)
+	require.Nil(t, err)
+	require.Nil(t, facetEngine.DeleteRecords([]string{"2"}))
+	_, facetGroups, err = facetEngine.Query()
+	require.Nil(t, err)
+	snapshot := facetEngine.Snapshot()
+	require.Equal(t, snapshot, facetEngine.Snapshot())
+
+	restored, _, _ := NewFacetEngine("", nil)
+	restoredGroups, err := restored.RestoreSnapshot(snapshot)
+	require.Nil(t, err)
+	expected, _ := json.Marshal(facetGroups)
+	actual, _ := json.Marshal(restoredGroups)
+	require.JSONEq(t, string(expected), string(actual))
+	require.Equal(t, categoricalFacetPath, restored.facetPath)
+	require.Equal(t, indexSnapshot(facetEngine), indexSnapshot(restored))
+
+	ids, _, err := restored.Query()
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"1", "3", "4"}, ids)
+	ordinals, err := restored.Ordinals([]string{"1", "3", "4"})
+	require.Nil(t, err)
+	require.Equal(t, []uint32{0, 2, 3}, ordinals)
+
+	_, err = restored.AddFilter("shaft (screwthread)", "pitch", Inclusive(2), Inclusive(2))
+	require.Nil(t, err)
+	ids, _, err = restored.Query()
+	require.Nil(t, err)
+	require.Equal(t, []string{"3"}, ids)
+
+	require.Nil(t, restored.AddRecords(`[{"id": "2", "bounds": [{"name": "shaft", "boundingType": {"name": "screwthread", "measurements": {"pitch": "2"}}}]}]`))
+	ids, _, err = restored.Query()
+	require.Nil(t, err)
+	require.ElementsMatch(t, []string{"2", "3"}, ids)
+	ordinals, err = restored.Ordinals([]string{"2"})
+	require.Nil(t, err)
+	require.Equal(t, []uint32{1}, ordinals)
+}
+
+func TestSnapshotEmpty(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("", nil)
+	restored := newFacetEngine()
+	facetGroups, err := restored.RestoreSnapshot(facetEngine.Snapshot())
+	require.Nil(t, err)
+	require.Empty(t, facetGroups)
+	require.True(t, restored.initialized)
+	require.Nil(t, restored.facetPath)
+}
+
+func TestRestoreSnapshotErrors(t *testing.T) {
+	facetEngine, _, err := NewFacetEngine(readmeExample, readmeFacetPath)
+	require.Nil(t, err)
+	snapshot := facetEngine.Snapshot()
+
+	_, err = facetEngine.RestoreSnapshot([]byte("not a snapshot"))
+	require.Equal(t, ErrInvalidArgument, ToError(err).Code)
+	newer := append([]byte(snapshotMagic), snapshotVersion+1)
+	_, err = facetEngine.RestoreSnapshot(append(newer, snapshot[len(newer):]...))
+	require.Contains(t, err.Error(), fmt.Sprintf("version %d is not supported", snapshotVersion+1))
+	_, err = facetEngine.RestoreSnapshot(append(append([]byte{}, snapshot...), 0))
+	require.Error(t, err)
+
+	// every truncation and every corrupted byte is an error or a different but usable index, never a panic.
+	for i := range snapshot {
+		_, err = newFacetEngine().RestoreSnapshot(snapshot[:i])
+		require.Error(t, err)
+		for _, b := range []byte{0x00, 0x7f, 0xff} {
+			corrupt := append([]byte{}, snapshot...)
+			corrupt[i] = b
+			restored := newFacetEngine()
+			if _, err := restored.RestoreSnapshot(corrupt); err == nil {
+				_, _, err = restored.Query()
+				require.Nil(t, err)
+				if restored.facetPath != nil {
+					require.Nil(t, restored.facetPath.validate())
+				}
+			}
+		}
+	}
+
+	// a facet path the engine would reject, and a chunk with more words than a chunk holds.
+	invalid := *readmeFacetPath
+	invalid.CaseFolding = "upper"
+	w := &snapshotWriter{}
+	w.facetPath(&invalid)
+	valid := &snapshotWriter{}
+	valid.facetPath(readmeFacetPath)
+	corrupt := bytes.Replace(snapshot, valid.buf.Bytes(), w.buf.Bytes(), 1)
+	_, err = newFacetEngine().RestoreSnapshot(corrupt)
+	require.Equal(t, ErrInvalidArgument, ToError(err).Code)
+	require.Contains(t, err.Error(), "caseFolding")
+	w = &snapshotWriter{}
+	w.bitmap(&Bitmap{chunks: []*chunk{{words: make([]uint64, chunkBits/64+1)}}})
+	r := &snapshotReader{data: w.buf.Bytes()}
+	r.bitmap()
+	require.Contains(t, r.err.Error(), "too many words")
+
+	ids, facetGroups, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(ids))
+	require.Equal(t, 2, facetGroups["area (cube)"].Count)
+}
+
+func TestRestoreSnapshotRetainRecords(t *testing.T) {
+	retained := *readmeFacetPath
+	retained.RetainRecords = true
+	facetEngine, _, err := NewFacetEngine(readmeExample, &retained)
+	require.Nil(t, err)
+	restored := newFacetEngine()
+	_, err = restored.RestoreSnapshot(facetEngine.Snapshot())
+	require.Nil(t, err)
+	require.False(t, restored.facetPath.RetainRecords)
+	require.False(t, restored.MemoryUsage().RetainRecords)
+	_, err = restored.Record("record 1")
+	require.Equal(t, ErrUnknownRecord, ToError(err).Code)
+	require.Contains(t, err.Error(), "retainRecords")
+	require.True(t, retained.RetainRecords)
+}
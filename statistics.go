@@ -0,0 +1,108 @@
+package facetengine
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Statistics summarise the values of a facet.  Count is the number of values, a record that has the facet more than
+// once contributes each of its distinct values.  Values that are infinite or NaN are not counted.  StdDev is the
+// population standard deviation.  Percentiles are keyed by the requested percentile, e.g. "50" or "99.9", and
+// interpolate linearly between the closest values.
+type Statistics struct {
+	Count       int                `json:"count"`
+	Min         float64            `json:"min"`
+	Max         float64            `json:"max"`
+	Sum         float64            `json:"sum"`
+	Mean        float64            `json:"mean"`
+	StdDev      float64            `json:"stdDev"`
+	Percentiles map[string]float64 `json:"percentiles,omitempty"`
+}
+
+// SetPercentiles choose which percentiles are included in the statistics of each facet returned from Query.
+func (f *FacetEngine) SetPercentiles(percentiles ...float64) error {
+	if err := validatePercentiles(percentiles); err != nil {
+		return err
+	}
+	f.percentiles = percentiles
+	return nil
+}
+
+// Statistics summarise the facet's values for the records matching the current filters.
+func (f FacetEngine) Statistics(facetGroupName string, facetName string, percentiles ...float64) (*Statistics, error) {
+	if err := validatePercentiles(percentiles); err != nil {
+		return nil, err
+	}
+	column, ok := f.RecordLookup[f.facetPath.key(facetGroupName, facetName)]
+	if !ok {
+		return nil, NewError(ErrUnknownFacet, "no facet named %s in %s", facetName, facetGroupName)
+	}
+	if column.Categorical {
+		return nil, NewError(ErrNotNumeric, "facet %s in %s is not numeric", facetName, facetGroupName)
+	}
+	matches := f.evaluate(f.query.Root)
+	// the same record can be in the column more than once for a value, only count each value a record has once.
+	seen := map[entry]bool{}
+	values := []float64{}
+	for i, ordinal := range column.Ordinals {
+		e := entry{ordinal: ordinal, value: column.Values[i]}
+		if !matches.Contains(ordinal) || seen[e] {
+			continue
+		}
+		seen[e] = true
+		values = append(values, column.Numbers[i])
+	}
+	return newStatistics(values, percentiles), nil
+}
+
+func validatePercentiles(percentiles []float64) error {
+	for _, percentile := range percentiles {
+		if !(percentile >= 0 && percentile <= 100) {
+			return NewError(ErrInvalidArgument, "percentile %v must be between 0 and 100", percentile)
+		}
+	}
+	return nil
+}
+
+// newStatistics summarise the values, skipping any that are infinite or NaN.  values is filtered, and sorted when
+// percentiles are requested, in place.
+func newStatistics(values []float64, percentiles []float64) *Statistics {
+	finite := values[:0]
+	for _, value := range values {
+		if !math.IsInf(value, 0) && !math.IsNaN(value) {
+			finite = append(finite, value)
+		}
+	}
+	values = finite
+	statistics := &Statistics{Count: len(values)}
+	if len(values) == 0 {
+		return statistics
+	}
+	statistics.Min = math.Inf(1)
+	statistics.Max = math.Inf(-1)
+	for _, value := range values {
+		statistics.Min = math.Min(statistics.Min, value)
+		statistics.Max = math.Max(statistics.Max, value)
+		statistics.Sum += value
+	}
+	statistics.Mean = statistics.Sum / float64(len(values))
+	variance := 0.0
+	for _, value := range values {
+		variance += (value - statistics.Mean) * (value - statistics.Mean)
+	}
+	statistics.StdDev = math.Sqrt(variance / float64(len(values)))
+	if len(percentiles) == 0 {
+		return statistics
+	}
+	sort.Float64s(values)
+	statistics.Percentiles = map[string]float64{}
+	for _, percentile := range percentiles {
+		rank := percentile / 100 * float64(len(values)-1)
+		lower := int(math.Floor(rank))
+		upper := int(math.Ceil(rank))
+		value := values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+		statistics.Percentiles[strconv.FormatFloat(percentile, 'f', -1, 64)] = value
+	}
+	return statistics
+}
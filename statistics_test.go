@@ -0,0 +1,78 @@
+package facetengine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatistics(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	statistics, err := facetEngine.Statistics("total-area (hex-cylinder)", "diameter", 0, 50, 75, 100)
+	require.Nil(t, err)
+	require.Equal(t, 3, statistics.Count)
+	require.Equal(t, 15.0, statistics.Min)
+	require.Equal(t, 16.0, statistics.Max)
+	require.Equal(t, 47.0, statistics.Sum)
+	require.InDelta(t, 15.6667, statistics.Mean, 0.0001)
+	require.InDelta(t, 0.4714, statistics.StdDev, 0.0001)
+	require.Equal(t, map[string]float64{"0": 15, "50": 16, "75": 16, "100": 16}, statistics.Percentiles)
+
+	facetEngine.AddFilter("total-area (hex-cylinder)", "weird", Inclusive(2), Inclusive(2))
+	statistics, err = facetEngine.Statistics("total-area (hex-cylinder)", "diameter")
+	require.Nil(t, err)
+	require.Equal(t, &Statistics{}, statistics)
+}
+
+func TestStatisticsInterpolate(t *testing.T) {
+	statistics := newStatistics([]float64{40, 10, 30, 20}, []float64{25, 50, 99.9})
+	require.Equal(t, 4, statistics.Count)
+	require.Equal(t, 25.0, statistics.Mean)
+	require.InDelta(t, 17.5, statistics.Percentiles["25"], 0.0001)
+	require.InDelta(t, 25, statistics.Percentiles["50"], 0.0001)
+	require.InDelta(t, 39.97, statistics.Percentiles["99.9"], 0.0001)
+}
+
+func TestStatisticsErrors(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine(readmeExample, readmeFacetPath)
+	_, err := facetEngine.Statistics("area (cube)", "side", -1)
+	require.Error(t, err)
+	_, err = facetEngine.Statistics("area (cube)", "missing")
+	require.Error(t, err)
+	require.Error(t, facetEngine.SetPercentiles(100.1))
+}
+
+func TestFacetStatistics(t *testing.T) {
+	facetEngine, facetGroups, _ := NewFacetEngine("["+object1+","+object2+","+object3+"]", defaultFacetPath)
+	require.Equal(t, &Statistics{Count: 1, Min: 1.5, Max: 1.5, Sum: 1.5, Mean: 1.5}, facetGroups["shaft (screwthread)"].Facets["pitch"].Statistics)
+	require.Nil(t, facetEngine.SetPercentiles(50))
+	facetEngine.AddFilter("total-area (hex-cylinder)", "weird", Inclusive(1), Inclusive(1))
+	_, facetGroups, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, &Statistics{Count: 2, Min: 16, Max: 16, Sum: 32, Mean: 16, Percentiles: map[string]float64{"50": 16}}, facetGroups["total-area (hex-cylinder)"].Facets["diameter"].Statistics)
+}
+
+func TestStatisticsNotFinite(t *testing.T) {
+	facetEngine, facetGroups, err := NewFacetEngine(`[
+		{"id": "1", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "NaN"}}}]},
+		{"id": "2", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "Inf"}}}]},
+		{"id": "3", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "-Inf"}}}]},
+		{"id": "4", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "10"}}}]}
+	]`, readmeFacetPath)
+	require.Nil(t, err)
+	require.Equal(t, &Statistics{Count: 1, Min: 10, Max: 10, Sum: 10, Mean: 10}, facetGroups["area (cube)"].Facets["side"].Statistics)
+	_, err = json.Marshal(facetGroups)
+	require.Nil(t, err)
+
+	require.Nil(t, facetEngine.SetPercentiles(50))
+	_, facetGroups, err = facetEngine.Query()
+	require.Nil(t, err)
+	_, err = json.Marshal(facetGroups)
+	require.Nil(t, err)
+	statistics, err := facetEngine.Statistics("area (cube)", "side", 50)
+	require.Nil(t, err)
+	require.Equal(t, &Statistics{Count: 1, Min: 10, Max: 10, Sum: 10, Mean: 10, Percentiles: map[string]float64{"50": 10}}, statistics)
+	_, err = json.Marshal(statistics)
+	require.Nil(t, err)
+}
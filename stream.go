@@ -0,0 +1,123 @@
+package facetengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Ingester indexes newline delimited json, one record per line, as it is written.  Only the line being decoded is
+// held as objects, so a large dataset never has to be a single string or a single tree of objects.  Records are
+// indexed as each line completes; a line may be split across writes.
+//
+// The first bad record stops the ingest.  The records before it stay indexed and every later Write or Close returns
+// the same error.
+type Ingester struct {
+	engine   *FacetEngine
+	progress func(records int)
+	pending  []byte
+	line     int
+	records  int
+	err      error
+}
+
+// NewIngester stream records in to the engine, which must already be initialized, e.g. with NewFacetEngine("",
+// facetPath).  progress, if not nil, is called with the total number of records indexed after every write that indexed
+// any.
+func (f *FacetEngine) NewIngester(progress func(records int)) (*Ingester, error) {
+	if !f.initialized || f.facetPath == nil {
+		return nil, NewError(ErrNotInitialized, "must initialize before ingesting records")
+	}
+	return &Ingester{engine: f, progress: progress}, nil
+}
+
+// IngestNDJSON index every record read from reader, returning how many were indexed.
+func (f *FacetEngine) IngestNDJSON(reader io.Reader, progress func(records int)) (int, error) {
+	ingester, err := f.NewIngester(progress)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.Copy(ingester, reader); err != nil {
+		return ingester.Records(), err
+	}
+	err = ingester.Close()
+	return ingester.Records(), err
+}
+
+// Write index every complete line in data, keeping a trailing partial line until the rest of it is written.  The
+// columns are sorted once per write rather than once per line.
+func (i *Ingester) Write(data []byte) (int, error) {
+	if i.err != nil {
+		return 0, i.err
+	}
+	defer i.engine.RecordLookup.sort()
+	before := i.records
+	i.pending = append(i.pending, data...)
+	start := 0
+	for {
+		end := bytes.IndexByte(i.pending[start:], '\n')
+		if end < 0 {
+			break
+		}
+		if err := i.ingestLine(i.pending[start : start+end]); err != nil {
+			return len(data), err
+		}
+		start += end + 1
+	}
+	i.pending = append(i.pending[:0], i.pending[start:]...)
+	i.report(before)
+	return len(data), nil
+}
+
+// Close index the last line when it has no trailing newline.
+func (i *Ingester) Close() error {
+	if i.err != nil {
+		return i.err
+	}
+	defer i.engine.RecordLookup.sort()
+	before := i.records
+	if err := i.ingestLine(i.pending); err != nil {
+		return err
+	}
+	i.pending = nil
+	i.report(before)
+	return nil
+}
+
+// Records return the number of records indexed so far.
+func (i *Ingester) Records() int {
+	return i.records
+}
+
+// ingestLine decode and index one record, blank lines are skipped.
+func (i *Ingester) ingestLine(line []byte) error {
+	i.line++
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+	var genericObject map[string]interface{}
+	if err := json.Unmarshal(line, &genericObject); err != nil {
+		return i.fail(err)
+	}
+	if err := i.engine.index([]map[string]interface{}{genericObject}, true); err != nil {
+		return i.fail(err)
+	}
+	i.records++
+	return nil
+}
+
+// fail keep the error, with the line number added to the message, for every later call.
+func (i *Ingester) fail(err error) error {
+	e := *ToError(err)
+	e.Message = fmt.Sprintf("line %d: %s", i.line, e.Message)
+	i.err = &e
+	return i.err
+}
+
+func (i *Ingester) report(before int) {
+	if i.progress != nil && i.records > before {
+		i.progress(i.records)
+	}
+}
@@ -0,0 +1,79 @@
+package facetengine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var ndjsonExample = `{"id": "record 1", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "10"}}}]}
+
+{"id": "record 2", "measurements": [{"measurementName": "area", "metrics": {"metricName": "cube", "measurements": {"side": "20"}}}]}
+{"id": "record 3", "measurements": [{"measurementName": "area", "metrics": {"metricName": "sphere", "measurements": {"diameter": "5"}}}]}`
+
+func TestIngestNDJSON(t *testing.T) {
+	facetEngine, _, err := NewFacetEngine("", readmeFacetPath)
+	require.Nil(t, err)
+	progress := []int{}
+	records, err := facetEngine.IngestNDJSON(strings.NewReader(ndjsonExample), func(records int) {
+		progress = append(progress, records)
+	})
+	require.Nil(t, err)
+	require.Equal(t, 3, records)
+	require.Equal(t, []int{2, 3}, progress)
+
+	expected, _, err := NewFacetEngine(`[`+strings.Replace(strings.Replace(ndjsonExample, "\n\n", "\n", 1), "\n", ",", -1)+`]`, readmeFacetPath)
+	require.Nil(t, err)
+	require.Equal(t, indexSnapshot(expected), indexSnapshot(facetEngine))
+	ids, facetGroups, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, 3, len(ids))
+	require.Equal(t, 2, facetGroups["area (cube)"].Count)
+}
+
+func TestIngesterChunks(t *testing.T) {
+	facetEngine, _, err := NewFacetEngine("", readmeFacetPath)
+	require.Nil(t, err)
+	progress := []int{}
+	ingester, err := facetEngine.NewIngester(func(records int) {
+		progress = append(progress, records)
+	})
+	require.Nil(t, err)
+	// split in the middle of the second record and leave the last line without a newline.
+	split := strings.Index(ndjsonExample, "record 2")
+	for _, chunk := range []string{ndjsonExample[:split], ndjsonExample[split:]} {
+		n, err := ingester.Write([]byte(chunk))
+		require.Nil(t, err)
+		require.Equal(t, len(chunk), n)
+	}
+	require.Equal(t, 2, ingester.Records())
+	require.Nil(t, ingester.Close())
+	require.Equal(t, 3, ingester.Records())
+	require.Equal(t, []int{1, 2, 3}, progress)
+}
+
+func TestIngesterErrors(t *testing.T) {
+	facetEngine, _, _ := NewFacetEngine("", nil)
+	_, err := facetEngine.NewIngester(nil)
+	require.Equal(t, ErrNotInitialized, ToError(err).Code)
+
+	facetEngine, _, _ = NewFacetEngine("", readmeFacetPath)
+	lines := strings.Split(ndjsonExample, "\n")
+	records, err := facetEngine.IngestNDJSON(strings.NewReader(lines[0]+"\n"+lines[0]+"\n"+lines[2]), nil)
+	require.Equal(t, 1, records)
+	require.Equal(t, ErrDuplicateRecord, ToError(err).Code)
+	require.Equal(t, "record 1", ToError(err).RecordID)
+	require.Contains(t, err.Error(), "line 2")
+
+	ingester, _ := facetEngine.NewIngester(nil)
+	_, err = ingester.Write([]byte("NOTJSON\n"))
+	require.Equal(t, ErrInvalidJSON, ToError(err).Code)
+	_, err = ingester.Write([]byte(lines[2]))
+	require.Equal(t, ErrInvalidJSON, ToError(err).Code)
+	require.Equal(t, ErrInvalidJSON, ToError(ingester.Close()).Code)
+
+	ids, _, err := facetEngine.Query()
+	require.Nil(t, err)
+	require.Equal(t, []string{"record 1"}, ids)
+}